@@ -127,6 +127,9 @@ var getApplianceHealthCheckCmd = &cobra.Command{
 	Short: "Get a last heartbeat of an appliance",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if plugin, _ := cmd.Flags().GetBool("plugin"); plugin {
+			return runApplianceHealthCheckPlugin(cmd, args[0])
+		}
 		response, err := client.GetApplianceHealthCheck(args[0])
 		if err != nil {
 			return err
@@ -176,6 +179,8 @@ func init() {
 	getApplianceConfigurationCmd.MarkFlagRequired("appliance-version")
 	getApplianceConfigurationCmd.MarkFlagRequired("plugins-path")
 
+	addPluginFlags(getApplianceHealthCheckCmd, "APPLIANCE", "age", "15m", "1h", "heartbeat age")
+
 	postApplianceHealthCheckCmd.Flags().String("appliance-version", "", "Appliance version")
 	postApplianceHealthCheckCmd.Flags().String("nagios-operating-state", "", "Nagios operating state (OK, WARNING, CRITICAL)")
 	postApplianceHealthCheckCmd.Flags().String("details", "", "Any details to explain the current operating state")
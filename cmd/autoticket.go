@@ -0,0 +1,705 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+const autoticketJournalFile = "autoticket-journal.json"
+
+// Actions of the decisions recorded in the journal, besides the triage
+// actions (attached, created, skipped, failed).
+const (
+	autoticketPending   = "pending"
+	autoticketApplying  = "applying"
+	autoticketRecovered = "recovered"
+)
+
+// autoticketMaxAttempts is the number of times a decision is tried when no
+// ticket could be created or attached.
+const autoticketMaxAttempts = 3
+
+// autoticketRetention is how long the applied decisions are kept in the
+// journal.
+const autoticketRetention = 30 * 24 * time.Hour
+
+var autoticketCmd = &cobra.Command{
+	Use:   "autoticket",
+	Short: "Attach or create tickets for new notifications, following rules",
+	Long: `Poll the new notifications and handle the ones matching a rule: attach them
+to the first ticket suggested, or create a ticket from a template.
+
+Rules are read from a YAML file and tried in order, the first matching rule
+applies:
+
+  rules:
+    - name: backup-failures
+      host-tags: [production]       # host tag IDs or labels, any of them
+      service: "^backup_"           # regular expression on the service name
+      states: [CRITICAL]            # default: every state but OK
+      duration: 15m                 # how long the state must last
+      action: attach-or-create      # attach, attach-or-create or create
+      ticket:                       # or template: <ticket template>
+        name: "{{.service}} {{.state}} on {{.host}}"
+        description: "{{.content}}"
+        owner: backup-team@example.com
+        catalog-items: ["Infrastructure > Backup"]
+
+Without a ticket or template, created tickets are named after the subject of
+the notification. The ticket fields are rendered with the variables of the
+notification: id, host, hostId, service, serviceId, state, subject, content
+and date.
+
+With a duration, the notification is handled once the service has been in its
+state for that long, and dropped when the service changes state before. Once a
+ticket is attached or created, the following notifications of the service in
+the same state go to that ticket.
+
+Decisions are recorded per Cloud Temple ID in autoticket-journal.json, in the
+configuration directory, so that a restarted daemon neither handles a
+notification twice nor forgets the pending ones. On the first run, only the
+notifications received from then on are handled, unless --since is given.`,
+	Args: cobra.NoArgs,
+	RunE: runAutoticket,
+}
+
+func init() {
+	rootCmd.AddCommand(autoticketCmd)
+	autoticketCmd.Flags().String("rules", "", "YAML file of the rules")
+	autoticketCmd.Flags().String("interval", "30s", "Polling interval")
+	autoticketCmd.Flags().String("since", "", "On the first run, also handle the latest notifications received during this period (e.g. 1h)")
+	autoticketCmd.Flags().Bool("once", false, "Handle the notifications received since the last run and exit")
+	autoticketCmd.Flags().Bool("dry-run", false, "Show the decisions without applying nor recording them")
+	autoticketCmd.MarkFlagRequired("rules")
+}
+
+// autoticketRule selects notifications and tells how their ticket is found.
+type autoticketRule struct {
+	Name string `yaml:"name"`
+	// HostTags are host tag IDs or labels, one of which the host must have
+	HostTags []string `yaml:"host-tags"`
+	// Service is a regular expression matched against the service name
+	Service  string   `yaml:"service"`
+	States   []string `yaml:"states"`
+	Duration string   `yaml:"duration"`
+	// Action is a triage policy: attach, attach-or-create or create
+	Action   string          `yaml:"action"`
+	Template string          `yaml:"template"`
+	Ticket   *ticketTemplate `yaml:"ticket"`
+
+	hostTags []int
+	service  *regexp.Regexp
+	duration time.Duration
+}
+
+// autoticketJournal is the state of the daemon for a tenant.
+type autoticketJournal struct {
+	Cursor notificationCursor `json:"cursor"`
+	// Decisions are keyed by notification ID
+	Decisions map[string]*autoticketDecision `json:"decisions"`
+	// Incidents holds the ticket of the services in a failed state, keyed
+	// like the services of the decisions
+	Incidents map[string]*autoticketIncident `json:"incidents"`
+}
+
+type autoticketDecision struct {
+	Notification string            `json:"notification"`
+	Rule         string            `json:"rule"`
+	Service      string            `json:"service"`
+	Action       string            `json:"action"`
+	Ticket       string            `json:"ticket,omitempty"`
+	Error        string            `json:"error,omitempty"`
+	Attempts     int               `json:"attempts,omitempty"`
+	Due          time.Time         `json:"due"`
+	Time         time.Time         `json:"time"`
+	Variables    map[string]string `json:"variables"`
+}
+
+type autoticketIncident struct {
+	State  string    `json:"state"`
+	Ticket string    `json:"ticket"`
+	Rule   string    `json:"rule"`
+	Since  time.Time `json:"since"`
+}
+
+type autoticketDaemon struct {
+	rules   []*autoticketRule
+	journal *autoticketJournal
+	dryRun  bool
+	format  string
+	since   time.Duration
+	save    func() error
+	// services caches the names of the services, by ID
+	services map[string]string
+}
+
+func runAutoticket(cmd *cobra.Command, args []string) error {
+	rulesPath, _ := cmd.Flags().GetString("rules")
+	intervalFlag, _ := cmd.Flags().GetString("interval")
+	sinceFlag, _ := cmd.Flags().GetString("since")
+	once, _ := cmd.Flags().GetBool("once")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	format, _ := cmd.Flags().GetString("format")
+
+	interval, err := parseDuration(intervalFlag)
+	if err != nil {
+		return err
+	}
+	if interval < time.Second {
+		return fmt.Errorf("interval must be at least 1s")
+	}
+	var since time.Duration
+	if sinceFlag != "" {
+		if since, err = parseDuration(sinceFlag); err != nil {
+			return err
+		}
+	}
+	rules, err := loadAutoticketRules(rulesPath)
+	if err != nil {
+		return err
+	}
+
+	journals := make(map[string]*autoticketJournal)
+	if err := loadState(autoticketJournalFile, &journals); err != nil {
+		return err
+	}
+	journal, ok := journals[cloudTempleID]
+	if !ok {
+		journal = &autoticketJournal{}
+		journals[cloudTempleID] = journal
+	}
+	if journal.Decisions == nil {
+		journal.Decisions = make(map[string]*autoticketDecision)
+	}
+	if journal.Incidents == nil {
+		journal.Incidents = make(map[string]*autoticketIncident)
+	}
+
+	d := &autoticketDaemon{
+		rules:    rules,
+		journal:  journal,
+		dryRun:   dryRun,
+		format:   format,
+		since:    since,
+		services: make(map[string]string),
+		save: func() error {
+			if dryRun {
+				return nil
+			}
+			return saveState(autoticketJournalFile, journals)
+		},
+	}
+	// A decision being applied when the daemon stopped may have created a
+	// ticket: it is not tried again.
+	for _, decision := range journal.Decisions {
+		if decision.Action == autoticketApplying {
+			decision.Action = "failed"
+			decision.Error = "interrupted while being applied, check the ticket of the notification"
+			decision.Time = time.Now()
+			d.print(decision)
+		}
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.poll(); err != nil {
+			if once {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		if once {
+			return nil
+		}
+		select {
+		case <-interrupt:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// loadAutoticketRules reads and checks the rules file.
+func loadAutoticketRules(path string) ([]*autoticketRule, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading rules: %w", err)
+	}
+	var file struct {
+		Rules []*autoticketRule `yaml:"rules"`
+	}
+	if err := yaml.UnmarshalStrict(content, &file); err != nil {
+		return nil, fmt.Errorf("invalid rules file %s: %w", path, err)
+	}
+	if len(file.Rules) == 0 {
+		return nil, fmt.Errorf("no rule in %s", path)
+	}
+
+	names := make(map[string]bool)
+	for i, r := range file.Rules {
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("#%d", i+1)
+		}
+		if names[r.Name] {
+			return nil, fmt.Errorf("duplicate rule name: %s", r.Name)
+		}
+		names[r.Name] = true
+
+		if r.Action == "" {
+			r.Action = triageAttachOrCreate
+		}
+		valid := false
+		for _, p := range triagePolicies {
+			valid = valid || p == r.Action
+		}
+		if !valid {
+			return nil, fmt.Errorf("invalid action %q in rule %s. Supported actions are %s", r.Action, r.Name, strings.Join(triagePolicies, ", "))
+		}
+		for j, state := range r.States {
+			r.States[j] = strings.ToUpper(state)
+			valid = false
+			for _, s := range nagiosStates {
+				valid = valid || s == r.States[j]
+			}
+			if !valid {
+				return nil, fmt.Errorf("invalid state %q in rule %s. Supported states are %s", state, r.Name, strings.Join(nagiosStates, ", "))
+			}
+		}
+		if r.Service != "" {
+			if r.service, err = regexp.Compile(r.Service); err != nil {
+				return nil, fmt.Errorf("invalid service expression in rule %s: %w", r.Name, err)
+			}
+		}
+		if r.Duration != "" {
+			if r.duration, err = parseDuration(r.Duration); err != nil {
+				return nil, fmt.Errorf("invalid duration in rule %s: %w", r.Name, err)
+			}
+		}
+		if len(r.HostTags) > 0 {
+			if r.hostTags, err = resolveHostTagIDs(r.HostTags); err != nil {
+				return nil, fmt.Errorf("rule %s: %w", r.Name, err)
+			}
+		}
+		if r.Template != "" && r.Ticket != nil {
+			return nil, fmt.Errorf("rule %s has both a template and a ticket", r.Name)
+		}
+		if r.Action != triageAttach {
+			if _, err := r.draft(notificationVariables(map[string]interface{}{})); err != nil {
+				return nil, fmt.Errorf("rule %s: %w", r.Name, err)
+			}
+		}
+	}
+	return file.Rules, nil
+}
+
+// draft prepares the ticket created for a notification.
+func (r *autoticketRule) draft(vars map[string]string) (*ticketTemplate, error) {
+	if r.Ticket == nil {
+		return notificationTicket(vars, r.Template, "", nil)
+	}
+	t := *r.Ticket
+	if err := renderTicketTemplate(&t, "the ticket", vars); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *autoticketRule) matchesState(state string) bool {
+	if len(r.States) == 0 {
+		return state != "" && state != "OK"
+	}
+	for _, s := range r.States {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// poll records the new notifications and applies the decisions that are due.
+func (d *autoticketDaemon) poll() error {
+	cursor := &d.journal.Cursor
+	firstRun := cursor.LastID == 0 && len(cursor.Seen) == 0
+	params := map[string]string{
+		"cloudTempleId": cloudTempleID,
+		"order":         "DESC",
+		"orderBy":       "id",
+	}
+	var notifications []map[string]interface{}
+	var err error
+	if firstRun && d.since > 0 {
+		notifications, err = fetchRecentNotifications(params, time.Now().Add(-d.since))
+	} else {
+		notifications, err = fetchNewNotifications(params, cursor, 1)
+	}
+	if err != nil {
+		return fmt.Errorf("error fetching notifications: %w", err)
+	}
+
+	hosts := make(map[string]map[string]interface{})
+	for _, n := range notifications {
+		if firstRun {
+			date := ticketDate(n, "createdAt", "date", "timestamp")
+			if d.since == 0 || date.IsZero() || time.Since(date) > d.since {
+				advanceNotificationCursor(cursor, n)
+				continue
+			}
+		}
+		if err := d.receive(n, hosts); err != nil {
+			d.fail(n, err)
+		}
+		advanceNotificationCursor(cursor, n)
+	}
+
+	for id, decision := range d.journal.Decisions {
+		if decision.Action != autoticketPending && time.Since(decision.Time) > autoticketRetention {
+			delete(d.journal.Decisions, id)
+		}
+	}
+	if err := d.save(); err != nil {
+		return err
+	}
+	return d.applyDue()
+}
+
+// fetchRecentNotifications returns the notifications received since a date,
+// oldest first, preceded by the most recent older one, which tells where to
+// place a cursor or the states before that date. Pages are read from the most
+// recent notification.
+func fetchRecentNotifications(params map[string]string, since time.Time) ([]map[string]interface{}, error) {
+	var notifications []map[string]interface{}
+	for page := 1; ; page++ {
+		pageParams := make(map[string]string)
+		for k, v := range params {
+			pageParams[k] = v
+		}
+		pageParams["page"] = strconv.Itoa(page)
+		pageParams["itemsPerPage"] = strconv.Itoa(batchSize)
+
+		response, err := client.GetAllNotifications(cloudTempleID, pageParams)
+		if err != nil {
+			return nil, err
+		}
+		data, err := decodeData(response)
+		if err != nil {
+			return nil, err
+		}
+		items, _ := data.([]interface{})
+
+		reachedSince := false
+		for _, item := range items {
+			n, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			notifications = append(notifications, n)
+			if date := ticketDate(n, "createdAt", "date", "timestamp"); !date.IsZero() && date.Before(since) {
+				reachedSince = true
+				break
+			}
+		}
+		if reachedSince || len(items) < batchSize {
+			break
+		}
+	}
+
+	for i, j := 0, len(notifications)-1; i < j; i, j = i+1, j-1 {
+		notifications[i], notifications[j] = notifications[j], notifications[i]
+	}
+	return notifications, nil
+}
+
+// fail records a notification that could not be received, for instance
+// because its host or service no longer exists, so that the following ones
+// are still handled.
+func (d *autoticketDaemon) fail(n map[string]interface{}, err error) {
+	vars := notificationVariables(n)
+	key := autoticketServiceKey(vars)
+	decision := &autoticketDecision{
+		Notification: resourceID(n),
+		Service:      key,
+		Action:       "failed",
+		Error:        err.Error(),
+		Time:         time.Now(),
+		Variables:    vars,
+	}
+	d.journal.Decisions[decision.Notification] = decision
+	d.print(decision)
+}
+
+// autoticketServiceKey identifies the service of a notification in the
+// journal.
+func autoticketServiceKey(vars map[string]string) string {
+	if vars["serviceId"] != "" {
+		return vars["serviceId"]
+	}
+	return vars["hostId"] + "/" + vars["service"]
+}
+
+// receive records the decision taken for a new notification, and drops the
+// pending decisions and the incident of its service when its state changed.
+func (d *autoticketDaemon) receive(n map[string]interface{}, hosts map[string]map[string]interface{}) error {
+	id := resourceID(n)
+	if _, ok := d.journal.Decisions[id]; ok {
+		return nil
+	}
+	vars := notificationVariables(n)
+	if vars["service"] == "" && vars["serviceId"] != "" {
+		name, err := d.serviceName(vars["serviceId"])
+		if err != nil {
+			return err
+		}
+		vars["service"] = name
+	}
+	key := autoticketServiceKey(vars)
+
+	if incident, ok := d.journal.Incidents[key]; ok && incident.State != vars["state"] {
+		delete(d.journal.Incidents, key)
+	}
+	for _, decision := range d.journal.Decisions {
+		if decision.Action == autoticketPending && decision.Service == key && decision.Variables["state"] != vars["state"] {
+			decision.Action = autoticketRecovered
+			decision.Time = time.Now()
+			d.print(decision)
+		}
+	}
+	if notificationTicketID(n) != "" {
+		return nil
+	}
+
+	rule, err := d.match(vars, hosts)
+	if err != nil || rule == nil {
+		return err
+	}
+	if vars["host"] == "" && vars["hostId"] != "" {
+		host, err := d.host(vars["hostId"], hosts)
+		if err != nil {
+			return err
+		}
+		vars["host"] = resourceName(host)
+	}
+	date := ticketDate(n, "createdAt", "date", "timestamp")
+	if date.IsZero() {
+		date = time.Now()
+	}
+	// The duration already elapsed for the incident of the service
+	if _, ok := d.journal.Incidents[key]; !ok {
+		date = date.Add(rule.duration)
+	}
+	decision := &autoticketDecision{
+		Notification: id,
+		Rule:         rule.Name,
+		Service:      key,
+		Action:       autoticketPending,
+		Due:          date,
+		Time:         time.Now(),
+		Variables:    vars,
+	}
+	d.journal.Decisions[id] = decision
+	if decision.Due.After(time.Now()) {
+		d.print(decision)
+	}
+	return nil
+}
+
+// match returns the first rule matching a notification, or nil.
+func (d *autoticketDaemon) match(vars map[string]string, hosts map[string]map[string]interface{}) (*autoticketRule, error) {
+	for _, r := range d.rules {
+		if !r.matchesState(vars["state"]) {
+			continue
+		}
+		if r.service != nil && !r.service.MatchString(vars["service"]) {
+			continue
+		}
+		if len(r.hostTags) > 0 {
+			if vars["hostId"] == "" {
+				continue
+			}
+			host, err := d.host(vars["hostId"], hosts)
+			if err != nil {
+				return nil, err
+			}
+			tagged := false
+			for _, tag := range hostTagIDs(host) {
+				for _, wanted := range r.hostTags {
+					tagged = tagged || tag == wanted
+				}
+			}
+			if !tagged {
+				continue
+			}
+		}
+		return r, nil
+	}
+	return nil, nil
+}
+
+// host returns a host, fetched once per poll.
+func (d *autoticketDaemon) host(id string, hosts map[string]map[string]interface{}) (map[string]interface{}, error) {
+	if host, ok := hosts[id]; ok {
+		return host, nil
+	}
+	response, err := client.GetHostDetails(id)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching host %s: %w", id, err)
+	}
+	data, err := decodeData(response)
+	if err != nil {
+		return nil, err
+	}
+	host, _ := data.(map[string]interface{})
+	hosts[id] = host
+	return host, nil
+}
+
+func (d *autoticketDaemon) serviceName(id string) (string, error) {
+	if name, ok := d.services[id]; ok {
+		return name, nil
+	}
+	response, err := client.GetMonitoringServiceDetails(id)
+	if err != nil {
+		return "", fmt.Errorf("error fetching monitoring service %s: %w", id, err)
+	}
+	data, err := decodeData(response)
+	if err != nil {
+		return "", err
+	}
+	service, _ := data.(map[string]interface{})
+	d.services[id] = resourceName(service)
+	return d.services[id], nil
+}
+
+// applyDue applies the pending decisions whose duration elapsed, oldest
+// notification first. The journal is saved before and after each of them.
+func (d *autoticketDaemon) applyDue() error {
+	var due []*autoticketDecision
+	for _, decision := range d.journal.Decisions {
+		if decision.Action == autoticketPending && !decision.Due.After(time.Now()) {
+			due = append(due, decision)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		a, _ := strconv.ParseInt(due[i].Notification, 10, 64)
+		b, _ := strconv.ParseInt(due[j].Notification, 10, 64)
+		return a < b
+	})
+
+	for _, decision := range due {
+		var rule *autoticketRule
+		for _, r := range d.rules {
+			if r.Name == decision.Rule {
+				rule = r
+			}
+		}
+		if rule == nil {
+			decision.Action = "skipped"
+			decision.Error = "the rule no longer exists"
+			decision.Time = time.Now()
+			d.print(decision)
+			continue
+		}
+
+		decision.Action = autoticketApplying
+		if err := d.save(); err != nil {
+			return err
+		}
+		action, ticketID, err := d.apply(decision, rule)
+		decision.Action = action
+		decision.Ticket = ticketID
+		decision.Error = ""
+		decision.Time = time.Now()
+		if err != nil {
+			decision.Action = "failed"
+			decision.Error = err.Error()
+			decision.Attempts++
+			if ticketID == "" && decision.Attempts < autoticketMaxAttempts {
+				decision.Action = autoticketPending
+			}
+		}
+		if incident, ok := d.journal.Incidents[decision.Service]; ticketID != "" && !d.dryRun && (!ok || incident.Ticket != ticketID) {
+			d.journal.Incidents[decision.Service] = &autoticketIncident{
+				State:  decision.Variables["state"],
+				Ticket: ticketID,
+				Rule:   rule.Name,
+				Since:  time.Now(),
+			}
+		}
+		d.print(decision)
+		if err := d.save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply attaches a notification to the ticket of the incident of its service,
+// or else follows the action of the rule.
+func (d *autoticketDaemon) apply(decision *autoticketDecision, rule *autoticketRule) (string, string, error) {
+	if incident, ok := d.journal.Incidents[decision.Service]; ok && incident.State == decision.Variables["state"] {
+		if d.dryRun {
+			return "attach", incident.Ticket, nil
+		}
+		return "attached", incident.Ticket, attachNotification(decision.Notification, incident.Ticket)
+	}
+	return triageAutoNotification(decision.Notification, rule.Action, func() (*ticketTemplate, error) {
+		return rule.draft(decision.Variables)
+	}, d.dryRun)
+}
+
+// print writes a decision as a JSON line for the json format, as a summary
+// otherwise.
+func (d *autoticketDaemon) print(decision *autoticketDecision) {
+	if d.format == "json" {
+		line, err := json.Marshal(decision)
+		if err == nil {
+			fmt.Println(string(line))
+		}
+		return
+	}
+
+	vars := decision.Variables
+	line := fmt.Sprintf("%s #%s", decision.Time.Format(time.RFC3339), decision.Notification)
+	for _, field := range []string{vars["host"], vars["service"], vars["state"]} {
+		if field != "" {
+			line += " " + field
+		}
+	}
+	if decision.Rule != "" {
+		line += fmt.Sprintf(" (rule %s)", decision.Rule)
+	}
+	line += ": " + decision.Action
+	if decision.Ticket != "" {
+		line += " ticket #" + decision.Ticket
+	}
+	if decision.Action == autoticketPending {
+		if decision.Error == "" {
+			line += " until " + decision.Due.Format(time.RFC3339)
+		} else {
+			line += fmt.Sprintf(", attempt %d of %d failed", decision.Attempts, autoticketMaxAttempts)
+		}
+	}
+	if decision.Error != "" {
+		line += ": " + decision.Error
+	}
+	if isTerminal(os.Stdout) {
+		line = highlight(line, "changed", decision.Variables["state"])
+	}
+	fmt.Println(line)
+}
@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/chrlesur/rtmscli/pkg/api"
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the local cache of API responses",
+	Long: `Manage the local cache of API responses.
+
+Reference data (catalogs, monitoring service templates, Nagios commands, teams
+and users) is cached on disk for a while, then revalidated with the API using
+ETag and Last-Modified when the API provides them. Use --refresh to fetch it
+again, or --no-cache to bypass the cache. These commands do not need an API
+key.`,
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+
+	// Clear cache
+	clearCacheCmd := &cobra.Command{
+		Use:   "clear",
+		Short: "Remove all cached API responses and completion data",
+		Args:  cobra.NoArgs,
+		RunE:  clearCache,
+	}
+	cacheCmd.AddCommand(clearCacheCmd)
+
+	// Cache stats
+	cacheStatsCmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Show the cached API responses per endpoint",
+		Args:  cobra.NoArgs,
+		RunE:  getCacheStats,
+	}
+	cacheCmd.AddCommand(cacheStatsCmd)
+}
+
+func clearCache(cmd *cobra.Command, args []string) error {
+	dir, err := cacheDir()
+	if err != nil {
+		return err
+	}
+	removed := 0
+	for _, sub := range []string{"http", "completion"} {
+		files, err := ioutil.ReadDir(filepath.Join(dir, sub))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error reading cache directory: %w", err)
+		}
+		for _, f := range files {
+			if err := os.Remove(filepath.Join(dir, sub, f.Name())); err != nil {
+				return fmt.Errorf("error clearing cache: %w", err)
+			}
+			removed++
+		}
+	}
+	fmt.Printf("Removed %d cached files from %s\n", removed, dir)
+	return nil
+}
+
+func getCacheStats(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	dir, err := cacheDir()
+	if err != nil {
+		return err
+	}
+	entries, err := api.ReadCache(filepath.Join(dir, "http"))
+	if err != nil {
+		return fmt.Errorf("error reading cache directory: %w", err)
+	}
+
+	type endpointStats struct {
+		entries, fresh, revalidable int
+		size                        int64
+		oldest                      time.Time
+	}
+	stats := make(map[string]*endpointStats)
+	now := time.Now()
+	for path, entry := range entries {
+		s, ok := stats[entry.Endpoint]
+		if !ok {
+			s = &endpointStats{}
+			stats[entry.Endpoint] = s
+		}
+		s.entries++
+		if now.Before(entry.Expires) {
+			s.fresh++
+		}
+		if entry.ETag != "" || entry.LastModified != "" {
+			s.revalidable++
+		}
+		if info, err := os.Stat(path); err == nil {
+			s.size += info.Size()
+		}
+		if s.oldest.IsZero() || entry.StoredAt.Before(s.oldest) {
+			s.oldest = entry.StoredAt
+		}
+	}
+
+	endpoints := make([]string, 0, len(stats))
+	for endpoint := range stats {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	rows := make([]interface{}, 0, len(endpoints)+1)
+	for _, endpoint := range endpoints {
+		s := stats[endpoint]
+		rows = append(rows, map[string]interface{}{
+			"endpoint":    endpoint,
+			"entries":     s.entries,
+			"fresh":       s.fresh,
+			"stale":       s.entries - s.fresh,
+			"revalidable": s.revalidable,
+			"bytes":       s.size,
+			"oldest":      s.oldest.Format(time.RFC3339),
+		})
+	}
+
+	// The shell completion data is listed as a whole
+	if files, err := ioutil.ReadDir(filepath.Join(dir, "completion")); err == nil && len(files) > 0 {
+		s := endpointStats{entries: len(files)}
+		for _, f := range files {
+			s.size += f.Size()
+			if now.Sub(f.ModTime()) < completionCacheTTL {
+				s.fresh++
+			}
+			if s.oldest.IsZero() || f.ModTime().Before(s.oldest) {
+				s.oldest = f.ModTime()
+			}
+		}
+		rows = append(rows, map[string]interface{}{
+			"endpoint":    "(shell completion)",
+			"entries":     s.entries,
+			"fresh":       s.fresh,
+			"stale":       s.entries - s.fresh,
+			"revalidable": 0,
+			"bytes":       s.size,
+			"oldest":      s.oldest.Format(time.RFC3339),
+		})
+	}
+
+	if len(rows) == 0 {
+		fmt.Printf("The cache in %s is empty\n", dir)
+		return nil
+	}
+	formattedOutput, err := formatOutput(map[string]interface{}{"data": rows}, format)
+	if err != nil {
+		return err
+	}
+	fmt.Println(formattedOutput)
+	return nil
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// completionCacheTTL is how long the resources fetched for shell completion
+// are reused, so that pressing Tab several times does not query the API each
+// time.
+const completionCacheTTL = time.Minute
+
+var outputFormats = []string{"json", "text", "html", "markdown", "table", "csv"}
+
+type completionEntry struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type completionCache struct {
+	Time    time.Time         `json:"time"`
+	Entries []completionEntry `json:"entries"`
+}
+
+func completeFormats(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return outputFormats, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completionEntries returns the ID and name of the resources of a list
+// endpoint, from the completion cache when it is recent enough.
+func completionEntries(endpoint string) ([]completionEntry, error) {
+	sum := sha1.Sum([]byte(host + "\n" + cloudTempleID + "\n" + endpoint))
+	path := ""
+	if dir, err := cacheDir(); err == nil && !noCache {
+		path = filepath.Join(dir, "completion", hex.EncodeToString(sum[:])+".json")
+		if content, err := ioutil.ReadFile(path); err == nil && !refreshCache {
+			var cache completionCache
+			if json.Unmarshal(content, &cache) == nil && time.Since(cache.Time) < completionCacheTTL {
+				return cache.Entries, nil
+			}
+		}
+	}
+
+	// Completion runs without the PersistentPreRunE of the root command
+	if err := initClient(); err != nil {
+		return nil, err
+	}
+	items, err := fetchAll(endpoint, map[string]string{"cloudTempleId": cloudTempleID})
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]completionEntry, 0, len(items))
+	for _, item := range items {
+		if id := resourceID(item); id != "" {
+			entries = append(entries, completionEntry{ID: id, Name: resourceName(item)})
+		}
+	}
+
+	if path != "" {
+		if content, err := json.Marshal(completionCache{Time: time.Now(), Entries: entries}); err == nil {
+			if os.MkdirAll(filepath.Dir(path), 0700) == nil {
+				ioutil.WriteFile(path, content, 0600)
+			}
+		}
+	}
+	return entries, nil
+}
+
+// completeIDs completes the first argument with the IDs of the resources of
+// an endpoint, described by their names.
+func completeIDs(endpoint string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		entries, err := completionEntries(endpoint)
+		if err != nil {
+			cobra.CompDebugln(err.Error(), true)
+			return nil, cobra.ShellCompDirectiveError
+		}
+		var completions []string
+		for _, e := range entries {
+			if strings.HasPrefix(e.ID, toComplete) {
+				completions = append(completions, e.ID+"\t"+e.Name)
+			}
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeNames completes a comma-separated list flag with the names of the
+// resources of an endpoint.
+func completeNames(endpoint string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		entries, err := completionEntries(endpoint)
+		if err != nil {
+			cobra.CompDebugln(err.Error(), true)
+			return nil, cobra.ShellCompDirectiveError
+		}
+		// Complete the last item of the list, keeping the previous ones
+		prefix, current := "", toComplete
+		if i := strings.LastIndex(toComplete, ","); i >= 0 {
+			prefix, current = toComplete[:i+1], toComplete[i+1:]
+		}
+		chosen := make(map[string]bool)
+		for _, name := range strings.Split(prefix, ",") {
+			chosen[strings.ToLower(name)] = true
+		}
+		var completions []string
+		for _, e := range entries {
+			if e.Name != "" && !chosen[strings.ToLower(e.Name)] && strings.HasPrefix(strings.ToLower(e.Name), strings.ToLower(current)) {
+				completions = append(completions, prefix+e.Name)
+			}
+		}
+		sort.Strings(completions)
+		return completions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+	}
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cliConfig holds the optional settings read from config.json in the
+// configuration directory.
+type cliConfig struct {
+	// Protected lists, per resource kind, the IDs or names that can never be
+	// deleted from the CLI.
+	Protected map[string][]string `json:"protected"`
+	// Cache holds the settings of the response cache.
+	Cache struct {
+		// TTL overrides, per endpoint prefix, how long responses are cached,
+		// such as "30m". "0" disables caching for the endpoint.
+		TTL map[string]string `json:"ttl"`
+	} `json:"cache"`
+}
+
+// configDir returns the directory holding the RTMS CLI configuration. It can be
+// overridden with the RTMS_CONFIG_DIR environment variable.
+func configDir() (string, error) {
+	if dir := os.Getenv("RTMS_CONFIG_DIR"); dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("error locating configuration directory: %w", err)
+	}
+	return filepath.Join(dir, "rtmscli"), nil
+}
+
+// cacheDir returns the directory holding the cached API responses. It can be
+// overridden with the RTMS_CACHE_DIR environment variable.
+func cacheDir() (string, error) {
+	if dir := os.Getenv("RTMS_CACHE_DIR"); dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("error locating cache directory: %w", err)
+	}
+	return filepath.Join(dir, "rtmscli"), nil
+}
+
+// loadConfig reads config.json from the configuration directory. A missing file
+// is not an error and yields an empty configuration.
+func loadConfig() (*cliConfig, error) {
+	cfg := &cliConfig{}
+
+	dir, err := configDir()
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(dir, "config.json")
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(content, cfg); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// isProtected reports whether the resource of the given kind, identified by its
+// ID or name, appears in the protected list.
+func (c *cliConfig) isProtected(kind, id, name string) bool {
+	for _, entry := range c.Protected[kind] {
+		if entry == id || (name != "" && strings.EqualFold(entry, name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadState decodes the JSON state file name from the configuration directory
+// into v. A missing file leaves v untouched.
+func loadState(name string, v interface{}) error {
+	dir, err := configDir()
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, name)
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(content, v); err != nil {
+		return fmt.Errorf("error parsing %s: %w", path, err)
+	}
+	return nil
+}
+
+// saveState writes v as JSON to the state file name in the configuration
+// directory, creating the directory if needed.
+func saveState(name string, v interface{}) error {
+	dir, err := configDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("error creating %s: %w", dir, err)
+	}
+
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, content, 0600); err != nil {
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,853 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/nsf/termbox-go"
+	"github.com/spf13/cobra"
+)
+
+// dashboardNotifications is the number of latest notifications shown.
+const dashboardNotifications = 50
+
+// The selectable panes of the dashboard, in the order Tab goes through them.
+const (
+	paneServices = iota
+	paneNotifications
+	paneTickets
+	paneCount
+)
+
+var paneTitles = [paneCount]string{
+	"CRITICAL/WARNING services",
+	"Latest notifications",
+	"Open unassigned tickets",
+}
+
+// dashboardData is the content of the panes, fetched at each refresh.
+type dashboardData struct {
+	hostStats     map[string]interface{}
+	services      []map[string]interface{}
+	notifications []map[string]interface{}
+	tickets       []map[string]interface{}
+	errors        []string
+	fetchedAt     time.Time
+}
+
+// dashboardHost is the host drilled into from a pane.
+type dashboardHost struct {
+	id       string
+	name     string
+	details  map[string]interface{}
+	services []map[string]interface{}
+	err      error
+	offset   int
+}
+
+// dashboardPrompt is a question asked on the status line.
+type dashboardPrompt struct {
+	label  string
+	value  string
+	submit func(string)
+}
+
+type dashboard struct {
+	interval   time.Duration
+	data       dashboardData
+	focus      int
+	selected   [paneCount]int
+	host       *dashboardHost
+	prompt     *dashboardPrompt
+	message    string
+	failed     bool
+	refreshing bool
+	pending    int
+	// monitoring holds the monitoring state of the hosts switched from the
+	// dashboard, for hosts whose details do not report it
+	monitoring map[string]bool
+	results    chan func()
+	done       chan struct{}
+}
+
+func init() {
+	// Dashboard
+	dashboardCmd := &cobra.Command{
+		Use:   "dashboard",
+		Short: "Show a live dashboard of hosts, services, notifications and tickets",
+		Long: `Show a full-screen dashboard refreshed at a fixed interval, with the host
+status counts, the CRITICAL and WARNING services, the latest notifications and
+the open tickets that are not assigned.
+
+Keys:
+  Tab, 1-3      Select the services, notifications or tickets pane
+  Up/Down, j/k  Select a row (PgUp/PgDn, Home/End to jump)
+  Enter         Show the details and services of the host of the row
+  Esc           Go back from the host details
+  a             Acknowledge the selected notification by attaching it to a
+                ticket, the suggested ticket being proposed
+  m             Enable or disable the monitoring of the host of the row
+  r             Refresh now
+  q, Ctrl+C     Quit`,
+		Args: cobra.NoArgs,
+		RunE: runDashboard,
+	}
+	dashboardCmd.Flags().String("interval", "15s", "Refresh interval")
+	rootCmd.AddCommand(dashboardCmd)
+}
+
+func runDashboard(cmd *cobra.Command, args []string) error {
+	intervalFlag, _ := cmd.Flags().GetString("interval")
+	interval, err := parseDuration(intervalFlag)
+	if err != nil {
+		return err
+	}
+	if interval < time.Second {
+		return fmt.Errorf("interval must be at least 1s")
+	}
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		return fmt.Errorf("the dashboard needs a terminal")
+	}
+	if debug {
+		return fmt.Errorf("the dashboard cannot be used in debug mode")
+	}
+
+	if err := termbox.Init(); err != nil {
+		return fmt.Errorf("error initializing the terminal: %w", err)
+	}
+	defer termbox.Close()
+
+	d := &dashboard{
+		interval:   interval,
+		monitoring: make(map[string]bool),
+		results:    make(chan func()),
+		done:       make(chan struct{}),
+	}
+	defer close(d.done)
+	return d.run()
+}
+
+func (d *dashboard) run() error {
+	// Events are read one at a time, so that no read is pending on exit
+	events := make(chan termbox.Event)
+	next := make(chan bool)
+	go func() {
+		for {
+			events <- termbox.PollEvent()
+			if !<-next {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	d.refresh()
+	for {
+		d.draw()
+		select {
+		case ev := <-events:
+			quit, err := d.handle(ev)
+			next <- !quit && err == nil
+			if quit || err != nil {
+				return err
+			}
+		case apply := <-d.results:
+			apply()
+		case <-ticker.C:
+			d.refresh()
+		}
+	}
+}
+
+// async runs an API call outside of the event loop, so that the screen stays
+// responsive, and applies the returned function on the loop.
+func (d *dashboard) async(call func() func()) {
+	d.pending++
+	go func() {
+		apply := call()
+		select {
+		case d.results <- func() {
+			d.pending--
+			apply()
+		}:
+		case <-d.done:
+		}
+	}()
+}
+
+func (d *dashboard) refresh() {
+	if d.host != nil {
+		d.loadHost()
+	}
+	if d.refreshing {
+		return
+	}
+	d.refreshing = true
+	d.async(func() func() {
+		data := fetchDashboardData()
+		return func() {
+			d.refreshing = false
+			d.data = data
+			for pane := 0; pane < paneCount; pane++ {
+				d.move(pane, 0)
+			}
+		}
+	})
+}
+
+func fetchDashboardData() dashboardData {
+	data := dashboardData{fetchedAt: time.Now()}
+	fail := func(what string, err error) {
+		data.errors = append(data.errors, fmt.Sprintf("%s: %v", what, err))
+	}
+
+	if response, err := client.GetHostsStats(cloudTempleID); err != nil {
+		fail("host stats", err)
+	} else if stats, err := decodeData(response); err != nil {
+		fail("host stats", err)
+	} else {
+		data.hostStats, _ = stats.(map[string]interface{})
+	}
+
+	services, err := dashboardList(client.GetMonitoringServices, map[string]string{
+		"status[]":     "CRITICAL,WARNING",
+		"itemsPerPage": strconv.Itoa(batchSize),
+	})
+	if err != nil {
+		fail("services", err)
+	}
+	for _, s := range services {
+		state := strings.ToUpper(fmt.Sprintf("%v", rowState(s)))
+		if state == "CRITICAL" || state == "WARNING" || rowState(s) == nil {
+			data.services = append(data.services, s)
+		}
+	}
+	// CRITICAL services first
+	sort.SliceStable(data.services, func(i, j int) bool {
+		return isFailedState(rowState(data.services[i])) && !isFailedState(rowState(data.services[j]))
+	})
+
+	data.notifications, err = dashboardList(client.GetAllNotifications, map[string]string{
+		"order":        "DESC",
+		"orderBy":      "id",
+		"itemsPerPage": strconv.Itoa(dashboardNotifications),
+	})
+	if err != nil {
+		fail("notifications", err)
+	}
+
+	tickets, err := dashboardList(client.GetTickets, map[string]string{
+		"isNotAssigned": "true",
+		"itemsPerPage":  strconv.Itoa(batchSize),
+	})
+	if err != nil {
+		fail("tickets", err)
+	}
+	for _, t := range tickets {
+		resolved := ticketDate(t, "resolvedAt", "resolutionDate", "closedAt", "closeDate", "closedDate")
+		if resolved.IsZero() && ticketOwnerName(t) == "" {
+			data.tickets = append(data.tickets, t)
+		}
+	}
+	return data
+}
+
+// dashboardList returns the items of the first page of a list.
+func dashboardList(get func(string, map[string]string) ([]byte, error), params map[string]string) ([]map[string]interface{}, error) {
+	response, err := get(cloudTempleID, params)
+	if err != nil {
+		return nil, err
+	}
+	data, err := decodeData(response)
+	if err != nil {
+		return nil, err
+	}
+	items, _ := data.([]interface{})
+	rows := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		if row, ok := item.(map[string]interface{}); ok {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+func (d *dashboard) loadHost() {
+	id := d.host.id
+	d.async(func() func() {
+		var details map[string]interface{}
+		var services []map[string]interface{}
+		response, err := client.GetHostDetails(id)
+		if err == nil {
+			var data interface{}
+			if data, err = decodeData(response); err == nil {
+				details, _ = data.(map[string]interface{})
+			}
+		}
+		if err == nil {
+			services, err = dashboardList(func(string, map[string]string) ([]byte, error) {
+				return client.GetHostServices(id, map[string]string{"itemsPerPage": strconv.Itoa(batchSize)})
+			}, nil)
+		}
+		return func() {
+			if d.host == nil || d.host.id != id {
+				return
+			}
+			d.host.err = err
+			if err == nil {
+				d.host.details, d.host.services = details, services
+				if name := resourceName(details); name != "" {
+					d.host.name = name
+				}
+			}
+		}
+	})
+}
+
+func (d *dashboard) rows(pane int) []map[string]interface{} {
+	switch pane {
+	case paneServices:
+		return d.data.services
+	case paneNotifications:
+		return d.data.notifications
+	default:
+		return d.data.tickets
+	}
+}
+
+func (d *dashboard) selectedRow() map[string]interface{} {
+	rows := d.rows(d.focus)
+	if len(rows) == 0 {
+		return nil
+	}
+	return rows[d.selected[d.focus]]
+}
+
+// move moves the selection of a pane, keeping it within its rows.
+func (d *dashboard) move(pane, delta int) {
+	selected := d.selected[pane] + delta
+	if n := len(d.rows(pane)); selected >= n {
+		selected = n - 1
+	}
+	if selected < 0 {
+		selected = 0
+	}
+	d.selected[pane] = selected
+}
+
+// currentHost returns the ID and name of the host shown, or of the host of
+// the selected row.
+func (d *dashboard) currentHost() (string, string) {
+	if d.host != nil {
+		return d.host.id, d.host.name
+	}
+	if row := d.selectedRow(); row != nil && d.focus != paneTickets {
+		return notificationHost(row)
+	}
+	return "", ""
+}
+
+func (d *dashboard) setMessage(failed bool, format string, args ...interface{}) {
+	d.message = fmt.Sprintf(format, args...)
+	d.failed = failed
+}
+
+func (d *dashboard) ask(label, value string, submit func(string)) {
+	d.prompt = &dashboardPrompt{label: label, value: value, submit: submit}
+}
+
+// handle processes a terminal event and reports whether to quit.
+func (d *dashboard) handle(ev termbox.Event) (bool, error) {
+	switch ev.Type {
+	case termbox.EventError:
+		return true, ev.Err
+	case termbox.EventKey:
+	default:
+		return false, nil
+	}
+	if ev.Key == termbox.KeyCtrlC {
+		return true, nil
+	}
+	if d.prompt != nil {
+		d.handlePrompt(ev)
+		return false, nil
+	}
+
+	d.message = ""
+	step := 0
+	switch {
+	case ev.Ch == 'q':
+		return true, nil
+	case ev.Ch == 'r':
+		d.refresh()
+	case ev.Ch == 'a':
+		d.acknowledge()
+	case ev.Ch == 'm':
+		d.toggleMonitoring()
+	case ev.Key == termbox.KeyEsc, ev.Key == termbox.KeyBackspace, ev.Key == termbox.KeyBackspace2, ev.Key == termbox.KeyArrowLeft:
+		d.host = nil
+	case ev.Key == termbox.KeyEnter, ev.Key == termbox.KeyArrowRight:
+		d.openHost()
+	case ev.Key == termbox.KeyTab && d.host == nil:
+		d.focus = (d.focus + 1) % paneCount
+	case ev.Ch >= '1' && ev.Ch < '1'+paneCount && d.host == nil:
+		d.focus = int(ev.Ch - '1')
+	case ev.Key == termbox.KeyArrowUp, ev.Ch == 'k':
+		step = -1
+	case ev.Key == termbox.KeyArrowDown, ev.Ch == 'j':
+		step = 1
+	case ev.Key == termbox.KeyPgup:
+		step = -10
+	case ev.Key == termbox.KeyPgdn:
+		step = 10
+	case ev.Key == termbox.KeyHome:
+		// Moves are bounded to the rows
+		step = -1 << 20
+	case ev.Key == termbox.KeyEnd:
+		step = 1 << 20
+	}
+	if step != 0 {
+		if d.host != nil {
+			d.host.offset += step
+			if d.host.offset < 0 {
+				d.host.offset = 0
+			}
+		} else {
+			d.move(d.focus, step)
+		}
+	}
+	return false, nil
+}
+
+func (d *dashboard) handlePrompt(ev termbox.Event) {
+	p := d.prompt
+	switch {
+	case ev.Key == termbox.KeyEsc:
+		d.prompt = nil
+	case ev.Key == termbox.KeyEnter:
+		d.prompt = nil
+		p.submit(strings.TrimSpace(p.value))
+	case ev.Key == termbox.KeyBackspace, ev.Key == termbox.KeyBackspace2:
+		if runes := []rune(p.value); len(runes) > 0 {
+			p.value = string(runes[:len(runes)-1])
+		}
+	case ev.Key == termbox.KeyCtrlU:
+		p.value = ""
+	case ev.Key == termbox.KeySpace:
+		p.value += " "
+	case ev.Ch != 0:
+		p.value += string(ev.Ch)
+	}
+}
+
+func (d *dashboard) openHost() {
+	if d.host != nil {
+		return
+	}
+	id, name := d.currentHost()
+	if id == "" {
+		d.setMessage(true, "No host for this row")
+		return
+	}
+	d.host = &dashboardHost{id: id, name: name}
+	d.loadHost()
+}
+
+// acknowledge attaches the selected notification to a ticket, proposing the
+// ticket suggested by the API.
+func (d *dashboard) acknowledge() {
+	if d.host != nil || d.focus != paneNotifications {
+		d.setMessage(true, "Select a notification to acknowledge in the notifications pane")
+		return
+	}
+	n := d.selectedRow()
+	if n == nil {
+		return
+	}
+	id := resourceID(n)
+	d.setMessage(false, "Fetching the ticket suggestions for notification #%s...", id)
+	d.async(func() func() {
+		suggestions, err := ticketSuggestions(id)
+		return func() {
+			d.message = ""
+			label := fmt.Sprintf("Attach notification #%s to ticket", id)
+			value := ""
+			switch {
+			case err != nil:
+				label += " (no suggestion: " + err.Error() + ")"
+			case len(suggestions) > 0:
+				value = resourceID(suggestions[0])
+				var hints []string
+				for i, s := range suggestions {
+					if i == 3 {
+						break
+					}
+					hints = append(hints, fmt.Sprintf("#%s %s", resourceID(s), resourceName(s)))
+				}
+				label += " (suggested: " + strings.Join(hints, ", ") + ")"
+			}
+			d.ask(label+": ", value, func(value string) {
+				ticketID, err := strconv.Atoi(value)
+				if err != nil || ticketID <= 0 {
+					d.setMessage(true, "Invalid ticket ID: %q", value)
+					return
+				}
+				d.async(func() func() {
+					_, err := client.AttachNotificationToTicket(id, ticketID)
+					return func() {
+						if err != nil {
+							d.setMessage(true, "Error attaching notification #%s: %v", id, err)
+							return
+						}
+						d.setMessage(false, "Notification #%s attached to ticket #%d", id, ticketID)
+						d.refresh()
+					}
+				})
+			})
+		}
+	})
+}
+
+func (d *dashboard) toggleMonitoring() {
+	id, name := d.currentHost()
+	if id == "" {
+		d.setMessage(true, "No host for this row")
+		return
+	}
+	label := id
+	if name != "" {
+		label = fmt.Sprintf("%s (%s)", name, id)
+	}
+	enable := !d.monitoringEnabled(id)
+	action := "Disable"
+	if enable {
+		action = "Enable"
+	}
+	d.ask(fmt.Sprintf("%s the monitoring of host %s? [y/N]: ", action, label), "", func(answer string) {
+		answer = strings.ToLower(answer)
+		if answer != "y" && answer != "yes" {
+			return
+		}
+		d.async(func() func() {
+			_, err := client.SwitchHostMonitoring(id, enable, nil)
+			return func() {
+				if err != nil {
+					d.setMessage(true, "Error switching the monitoring of host %s: %v", label, err)
+					return
+				}
+				d.monitoring[id] = enable
+				d.setMessage(false, "Monitoring of host %s %sd", label, strings.ToLower(action))
+				d.refresh()
+			}
+		})
+	})
+}
+
+// monitoringEnabled tells whether the monitoring of a host is enabled, as
+// reported by its details or else as last switched from the dashboard.
+func (d *dashboard) monitoringEnabled(id string) bool {
+	if d.host != nil && d.host.id == id {
+		if enabled, ok := hostMonitoringState(d.host.details); ok {
+			return enabled
+		}
+	}
+	if enabled, ok := d.monitoring[id]; ok {
+		return enabled
+	}
+	return true
+}
+
+func hostMonitoringState(host map[string]interface{}) (bool, bool) {
+	for _, key := range []string{"monitoringEnabled", "isMonitored", "monitored", "monitoring"} {
+		if enabled, ok := host[key].(bool); ok {
+			return enabled, true
+		}
+	}
+	return false, false
+}
+
+// Drawing
+
+const (
+	dashboardHelp     = "Tab/1-3 pane  ↑↓ select  Enter host  a acknowledge  m monitoring  r refresh  q quit"
+	dashboardHostHelp = "Esc back  ↑↓ scroll  m monitoring  r refresh  q quit"
+)
+
+func (d *dashboard) draw() {
+	termbox.Clear(termbox.ColorDefault, termbox.ColorDefault)
+	termbox.HideCursor()
+	width, height := termbox.Size()
+
+	title := " RTMS dashboard - " + cloudTempleID
+	if d.host != nil {
+		title = fmt.Sprintf(" Host %s (%s) - %s", d.host.name, d.host.id, cloudTempleID)
+	}
+	status := "loading"
+	if !d.data.fetchedAt.IsZero() {
+		status = "updated " + d.data.fetchedAt.Format("15:04:05")
+	}
+	if d.pending > 0 {
+		status = "refreshing... " + status
+	}
+	status += fmt.Sprintf(", every %s ", d.interval)
+	fillLine(0, termbox.AttrReverse)
+	drawText(0, 0, title, termbox.AttrReverse|termbox.AttrBold, termbox.AttrReverse)
+	drawText(width-runewidth.StringWidth(status), 0, status, termbox.AttrReverse, termbox.AttrReverse)
+
+	help := dashboardHelp
+	if d.host != nil {
+		d.drawHost(1, height-2)
+		help = dashboardHostHelp
+	} else {
+		d.drawPanes(1, height-2)
+	}
+
+	switch {
+	case d.prompt != nil:
+		x := drawText(0, height-2, d.prompt.label, termbox.AttrBold, termbox.ColorDefault)
+		x = drawText(x, height-2, d.prompt.value, termbox.ColorDefault, termbox.ColorDefault)
+		termbox.SetCursor(x, height-2)
+	case d.message != "":
+		color := termbox.ColorGreen
+		if d.failed {
+			color = termbox.ColorRed
+		}
+		drawText(0, height-2, d.message, color, termbox.ColorDefault)
+	case len(d.data.errors) > 0:
+		drawText(0, height-2, "Error fetching "+strings.Join(d.data.errors, "; "), termbox.ColorRed, termbox.ColorDefault)
+	}
+	drawText(0, height-1, help, termbox.ColorCyan, termbox.ColorDefault)
+	termbox.Flush()
+}
+
+// drawPanes draws the host stats and the panes between the lines top and
+// bottom (excluded).
+func (d *dashboard) drawPanes(top, bottom int) {
+	x := drawText(0, top, "Hosts:", termbox.AttrBold, termbox.ColorDefault)
+	statuses := make([]string, 0, len(d.data.hostStats))
+	for status := range d.data.hostStats {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	for _, status := range statuses {
+		x = drawText(x+2, top, fmt.Sprintf("%s %v", status, d.data.hostStats[status]), stateColor(status), termbox.ColorDefault)
+	}
+	top += 2
+
+	available := bottom - top
+	for pane := 0; pane < paneCount; pane++ {
+		height := available / paneCount
+		if pane == paneCount-1 {
+			height = available - height*(paneCount-1)
+		}
+		d.drawPane(pane, top, height)
+		top += height
+	}
+}
+
+func (d *dashboard) drawPane(pane, top, height int) {
+	if height < 1 {
+		return
+	}
+	rows := d.rows(pane)
+	title := fmt.Sprintf(" %d %s (%d) ", pane+1, paneTitles[pane], len(rows))
+	if pane == d.focus {
+		fillLine(top, termbox.ColorBlue)
+		drawText(0, top, title, termbox.ColorWhite|termbox.AttrBold, termbox.ColorBlue)
+	} else {
+		drawText(0, top, title, termbox.AttrBold, termbox.ColorDefault)
+	}
+
+	lines := height - 1
+	offset := 0
+	if selected := d.selected[pane]; selected >= lines {
+		offset = selected - lines + 1
+	}
+	for i := 0; i < lines && offset+i < len(rows); i++ {
+		row := rows[offset+i]
+		var text string
+		fg := termbox.ColorDefault
+		switch pane {
+		case paneServices:
+			text = serviceLine(row)
+			fg = stateColor(rowState(row))
+		case paneNotifications:
+			text = notificationLine(row)
+			fg = stateColor(rowState(row))
+		case paneTickets:
+			text = ticketLine(row)
+		}
+		bg := termbox.ColorDefault
+		if pane == d.focus && offset+i == d.selected[pane] {
+			fg, bg = fg|termbox.AttrReverse, bg|termbox.AttrReverse
+			fillLine(top+1+i, bg)
+		}
+		drawText(1, top+1+i, text, fg, bg)
+	}
+}
+
+// drawHost draws the details and services of the host drilled into.
+func (d *dashboard) drawHost(top, bottom int) {
+	h := d.host
+	type line struct {
+		text string
+		fg   termbox.Attribute
+	}
+	var lines []line
+	switch {
+	case h.err != nil:
+		lines = append(lines, line{fmt.Sprintf("Error fetching host %s: %v", h.id, h.err), termbox.ColorRed})
+	case h.details == nil:
+		lines = append(lines, line{"Loading...", termbox.ColorDefault})
+	default:
+		keys := make([]string, 0, len(h.details))
+		for key := range h.details {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fg := termbox.ColorDefault
+			if key == "status" || key == "state" {
+				fg = stateColor(h.details[key])
+			}
+			lines = append(lines, line{fmt.Sprintf("%-20s %s", key, dashboardValue(h.details[key])), fg})
+		}
+		if _, known := hostMonitoringState(h.details); !known {
+			if enabled, ok := d.monitoring[h.id]; ok {
+				state := "disabled"
+				if enabled {
+					state = "enabled"
+				}
+				lines = append(lines, line{fmt.Sprintf("%-20s %s (switched from the dashboard)", "monitoring", state), termbox.ColorDefault})
+			}
+		}
+		lines = append(lines, line{"", termbox.ColorDefault})
+		lines = append(lines, line{fmt.Sprintf("Services (%d)", len(h.services)), termbox.AttrBold})
+		for _, s := range h.services {
+			lines = append(lines, line{serviceLine(s), stateColor(rowState(s))})
+		}
+	}
+
+	if last := len(lines) - (bottom - top - 1); h.offset > last {
+		h.offset = last
+	}
+	if h.offset < 0 {
+		h.offset = 0
+	}
+	for i := 0; top+1+i < bottom && h.offset+i < len(lines); i++ {
+		l := lines[h.offset+i]
+		drawText(1, top+1+i, l.text, l.fg, termbox.ColorDefault)
+	}
+}
+
+func serviceLine(s map[string]interface{}) string {
+	_, hostName := notificationHost(s)
+	text := fmt.Sprintf("%-9s %-24s %s", dashboardValue(rowState(s)), hostName, resourceName(s))
+	for _, key := range []string{"output", "pluginOutput", "message"} {
+		if output, ok := s[key].(string); ok && output != "" {
+			text += "  " + output
+			break
+		}
+	}
+	return text
+}
+
+func notificationLine(n map[string]interface{}) string {
+	date := ""
+	if t := ticketDate(n, "createdAt", "date", "timestamp"); !t.IsZero() {
+		date = t.Local().Format("2006-01-02 15:04")
+	}
+	_, hostName := notificationHost(n)
+	subject := n["subject"]
+	if subject == nil {
+		subject = n["content"]
+	}
+	text := fmt.Sprintf("%-16s #%-6s %-9s %s: %s", date, resourceID(n), dashboardValue(rowState(n)), hostName, dashboardValue(subject))
+	if ticketID := notificationTicketID(n); ticketID != "" {
+		text += fmt.Sprintf("  [ticket #%s]", ticketID)
+	}
+	return text
+}
+
+func ticketLine(t map[string]interface{}) string {
+	date := ""
+	if created := ticketDate(t, "createdAt", "creationDate", "date"); !created.IsZero() {
+		date = created.Local().Format("2006-01-02 15:04")
+	}
+	text := fmt.Sprintf("#%-6s %-16s %s", resourceID(t), date, resourceName(t))
+	if items := ticketCatalogItems(t); len(items) > 0 {
+		text += "  (" + strings.Join(items, ", ") + ")"
+	}
+	return text
+}
+
+// dashboardValue formats a field on one line: lists of resources by name,
+// other objects as compact JSON.
+func dashboardValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.Join(strings.Fields(v), " ")
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []interface{}:
+		names := make([]string, 0, len(v))
+		for _, item := range v {
+			name := resourceName(item)
+			if name == "" {
+				name = dashboardValue(item)
+			}
+			names = append(names, name)
+		}
+		return strings.Join(names, ", ")
+	case map[string]interface{}:
+		if name := resourceName(v); name != "" {
+			return name
+		}
+	}
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
+
+func stateColor(state interface{}) termbox.Attribute {
+	switch {
+	case isFailedState(state):
+		return termbox.ColorRed
+	case isHealthyState(state):
+		return termbox.ColorGreen
+	case strings.EqualFold(fmt.Sprintf("%v", state), "WARNING"):
+		return termbox.ColorYellow
+	}
+	return termbox.ColorDefault
+}
+
+// drawText writes text from the column x, cut at the edge of the screen, and
+// returns the column following it.
+func drawText(x, y int, text string, fg, bg termbox.Attribute) int {
+	width, _ := termbox.Size()
+	for _, r := range text {
+		w := runewidth.RuneWidth(r)
+		if x+w > width {
+			break
+		}
+		termbox.SetCell(x, y, r, fg, bg)
+		x += w
+	}
+	return x
+}
+
+func fillLine(y int, bg termbox.Attribute) {
+	width, _ := termbox.Size()
+	for x := 0; x < width; x++ {
+		termbox.SetCell(x, y, ' ', termbox.ColorDefault, bg)
+	}
+}
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// composeInEditor opens the editor of the user on a document made of a YAML
+// front matter and a body, and returns them once edited. The hint is written
+// as comments at the top of the front matter.
+func composeInEditor(hint string, frontMatter yaml.MapSlice, body string) (map[string]interface{}, string, error) {
+	header, err := yaml.Marshal(frontMatter)
+	if err != nil {
+		return nil, "", err
+	}
+	var document strings.Builder
+	document.WriteString("---\n")
+	for _, line := range strings.Split(hint, "\n") {
+		document.WriteString("# " + line + "\n")
+	}
+	document.Write(header)
+	document.WriteString("---\n")
+	document.WriteString(body)
+
+	file, err := ioutil.TempFile("", "rtmscli-*.md")
+	if err != nil {
+		return nil, "", err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString(document.String()); err != nil {
+		file.Close()
+		return nil, "", err
+	}
+	file.Close()
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	// The editor may come with arguments, such as "code --wait"
+	fields := strings.Fields(editor)
+	command := exec.Command(fields[0], append(fields[1:], file.Name())...)
+	command.Stdin = os.Stdin
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+	if err := command.Run(); err != nil {
+		return nil, "", fmt.Errorf("error running editor %s: %w", editor, err)
+	}
+
+	content, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		return nil, "", err
+	}
+	return parseFrontMatter(string(content))
+}
+
+// parseFrontMatter splits a document into its YAML front matter, delimited by
+// "---" lines, and its body.
+func parseFrontMatter(content string) (map[string]interface{}, string, error) {
+	values := make(map[string]interface{})
+	content = strings.Replace(content, "\r\n", "\n", -1)
+	if !strings.HasPrefix(content, "---\n") {
+		return values, strings.TrimSpace(content), nil
+	}
+	rest := content[len("---\n"):]
+	end := strings.Index(rest, "\n---")
+	if end < 0 {
+		return nil, "", fmt.Errorf("front matter is not closed by a --- line")
+	}
+	if err := yaml.Unmarshal([]byte(rest[:end]), &values); err != nil {
+		return nil, "", fmt.Errorf("invalid front matter: %w", err)
+	}
+	body := rest[end+len("\n---"):]
+	if i := strings.Index(body, "\n"); i >= 0 {
+		body = body[i+1:]
+	} else {
+		body = ""
+	}
+	return values, strings.TrimSpace(body), nil
+}
+
+// readContentFile reads a file, or the standard input when path is "-".
+func readContentFile(path string) (string, error) {
+	var content []byte
+	var err error
+	if path == "-" {
+		content, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		content, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	return strings.TrimRight(string(content), "\n"), nil
+}
+
+func frontMatterString(values map[string]interface{}, key string) string {
+	if value, ok := values[key]; ok && value != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return ""
+}
+
+func frontMatterInt(values map[string]interface{}, key string) (int, error) {
+	switch value := values[key].(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return value, nil
+	case string:
+		if value == "" {
+			return 0, nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, fmt.Errorf("%s must be a number", key)
+		}
+		return n, nil
+	}
+	return 0, fmt.Errorf("%s must be a number", key)
+}
+
+func frontMatterStrings(values map[string]interface{}, key string) ([]string, error) {
+	switch value := values[key].(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		strs := make([]string, 0, len(value))
+		for _, item := range value {
+			switch item.(type) {
+			case string, int, float64:
+				strs = append(strs, fmt.Sprintf("%v", item))
+			default:
+				return nil, fmt.Errorf("%s must be a list of values", key)
+			}
+		}
+		return strs, nil
+	case string, int, float64:
+		return []string{fmt.Sprintf("%v", value)}, nil
+	}
+	return nil, fmt.Errorf("%s must be a list of values", key)
+}
+
+func frontMatterBool(values map[string]interface{}, key string) (bool, error) {
+	switch value := values[key].(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return value, nil
+	}
+	return false, fmt.Errorf("%s must be true or false", key)
+}
@@ -0,0 +1,352 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var exporterCmd = &cobra.Command{
+	Use:   "exporter",
+	Short: "Expose RTMS metrics to Prometheus",
+	Long: `Serve the RTMS state on /metrics in the Prometheus text exposition format.
+
+Host, monitoring service and ticket statistics, the RTMS health and the
+appliance heartbeats are collected for each Cloud Temple ID every --interval,
+in the background. Scrapes are served the last collected metrics, so that they
+neither hit the API nor wait for it.`,
+	RunE: runExporter,
+}
+
+func init() {
+	rootCmd.AddCommand(exporterCmd)
+	exporterCmd.Flags().String("listen", ":9810", "Address the exporter listens on")
+	exporterCmd.Flags().StringSlice("cloud-temple-ids", nil, "Cloud Temple IDs to collect (default: the global --cloud-temple-id)")
+	exporterCmd.Flags().String("interval", "60s", "Interval between two collections of the metrics")
+	exporterCmd.Flags().Bool("appliances", true, "Collect the heartbeats of the appliances")
+	exporterCmd.Flags().IntSlice("integration-services", nil, "Service identifiers passed to the RTMS health check")
+	exporterCmd.Flags().Int("integration-delay", 0, "Integration delay in seconds passed to the RTMS health check")
+}
+
+// rtmsExporter collects the metrics of the RTMS API periodically and serves
+// the last collection.
+type rtmsExporter struct {
+	tenants             []string
+	appliances          bool
+	integrationServices []int
+	integrationDelay    int
+	// apiErrors is only used by the collection
+	apiErrors map[string]float64
+
+	mu     sync.Mutex
+	cached []byte
+}
+
+func runExporter(cmd *cobra.Command, args []string) error {
+	listen, _ := cmd.Flags().GetString("listen")
+	tenants, _ := cmd.Flags().GetStringSlice("cloud-temple-ids")
+	intervalFlag, _ := cmd.Flags().GetString("interval")
+	appliances, _ := cmd.Flags().GetBool("appliances")
+	integrationServices, _ := cmd.Flags().GetIntSlice("integration-services")
+	integrationDelay, _ := cmd.Flags().GetInt("integration-delay")
+
+	if len(tenants) == 0 && cloudTempleID != "" {
+		tenants = []string{cloudTempleID}
+	}
+	if len(tenants) == 0 {
+		return fmt.Errorf("at least one Cloud Temple ID is required, use --cloud-temple-ids or --cloud-temple-id")
+	}
+	interval, err := parseDuration(intervalFlag)
+	if err != nil {
+		return err
+	}
+	if interval < time.Second {
+		return fmt.Errorf("interval must be at least 1s")
+	}
+
+	exporter := &rtmsExporter{
+		tenants:             tenants,
+		appliances:          appliances,
+		integrationServices: integrationServices,
+		integrationDelay:    integrationDelay,
+		apiErrors:           make(map[string]float64),
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", exporter)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head><title>RTMS Exporter</title></head><body><h1>RTMS Exporter</h1><p><a href="/metrics">Metrics</a></p></body></html>`)
+	})
+
+	// The first collection is done before listening, so that scrapes are
+	// never served empty metrics
+	exporter.update()
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				exporter.update()
+			}
+		}
+	}()
+
+	fmt.Printf("Serving RTMS metrics for %s on %s/metrics\n", strings.Join(tenants, ", "), listen)
+	server := &http.Server{Addr: listen, Handler: mux}
+	// The shell stops the exporter on Ctrl+C
+	go func() {
+		select {
+		case <-commandContext.Done():
+			server.Close()
+		case <-stop:
+		}
+	}()
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// update collects the metrics and replaces the ones served.
+func (e *rtmsExporter) update() {
+	metrics := e.collect()
+	e.mu.Lock()
+	e.cached = metrics
+	e.mu.Unlock()
+}
+
+// ServeHTTP serves the last collected metrics.
+func (e *rtmsExporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	body := e.cached
+	e.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(body)
+}
+
+// collect calls the API and renders every metric. It is only called by the
+// collection, one at a time.
+func (e *rtmsExporter) collect() []byte {
+	metrics := newMetricsWriter()
+	start := time.Now()
+
+	healthStart := time.Now()
+	response, err := client.CheckRTMSHealth(e.integrationServices, e.integrationDelay)
+	health := pluginCritical
+	if err != nil {
+		e.apiErrors["health"]++
+	} else if data, err := decodeData(response); err == nil {
+		health = pluginOK
+		for _, problem := range healthProblems(data, "") {
+			if pluginSeverity[problem.status] > pluginSeverity[health] {
+				health = problem.status
+			}
+		}
+	} else {
+		e.apiErrors["health"]++
+	}
+	metrics.add("rtms_health_status", "gauge", "RTMS health as a Nagios return code (0 OK, 1 WARNING, 2 CRITICAL, 3 UNKNOWN).", nil, float64(health))
+	metrics.add("rtms_health_response_seconds", "gauge", "Response time of the RTMS health check.", nil, time.Since(healthStart).Seconds())
+
+	for _, tenant := range e.tenants {
+		tenantStart := time.Now()
+		labels := map[string]string{"cloud_temple_id": tenant}
+
+		stats := []struct {
+			endpoint string
+			name     string
+			help     string
+			fetch    func() ([]byte, error)
+		}{
+			{"hosts_stats", "rtms_hosts", "Number of hosts per status.", func() ([]byte, error) {
+				return client.GetHostsStats(tenant)
+			}},
+			{"monitoring_services_stats", "rtms_monitoring_services", "Number of monitoring services per status.", func() ([]byte, error) {
+				return client.GetMonitoringServicesStats(tenant, map[string]string{"cloudTempleId": tenant})
+			}},
+			{"tickets_stats", "rtms_tickets", "Number of tickets per status.", func() ([]byte, error) {
+				return client.GetTicketsStats(tenant)
+			}},
+		}
+		for _, s := range stats {
+			response, err := s.fetch()
+			if err != nil {
+				e.apiErrors[s.endpoint]++
+				continue
+			}
+			data, err := decodeData(response)
+			if err != nil {
+				e.apiErrors[s.endpoint]++
+				continue
+			}
+			for status, value := range flattenNumbers(data, "") {
+				metrics.add(s.name, "gauge", s.help, withLabel(labels, "status", status), value)
+			}
+		}
+
+		if e.appliances {
+			e.collectAppliances(metrics, tenant, labels)
+		}
+
+		metrics.add("rtms_tenant_scrape_duration_seconds", "gauge", "Time spent collecting the metrics of a Cloud Temple ID.", labels, time.Since(tenantStart).Seconds())
+	}
+
+	endpoints := make([]string, 0, len(e.apiErrors))
+	for endpoint := range e.apiErrors {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	for _, endpoint := range endpoints {
+		metrics.add("rtms_api_errors_total", "counter", "Number of failed RTMS API calls.", map[string]string{"endpoint": endpoint}, e.apiErrors[endpoint])
+	}
+	metrics.add("rtms_scrape_duration_seconds", "gauge", "Time spent collecting all the metrics.", nil, time.Since(start).Seconds())
+	metrics.add("rtms_last_collect_timestamp_seconds", "gauge", "Unix time of the last collection.", nil, float64(time.Now().Unix()))
+
+	return metrics.bytes()
+}
+
+func (e *rtmsExporter) collectAppliances(metrics *metricsWriter, tenant string, labels map[string]string) {
+	response, err := client.GetAppliances(tenant)
+	if err != nil {
+		e.apiErrors["appliances"]++
+		return
+	}
+	data, err := decodeData(response)
+	if err != nil {
+		e.apiErrors["appliances"]++
+		return
+	}
+	appliances, _ := data.([]interface{})
+	for _, appliance := range appliances {
+		id := resourceID(appliance)
+		if id == "" {
+			continue
+		}
+		applianceLabels := withLabel(withLabel(labels, "appliance_id", id), "appliance", resourceName(appliance))
+
+		response, err := client.GetApplianceHealthCheck(id)
+		if err != nil {
+			e.apiErrors["appliance_healthcheck"]++
+			continue
+		}
+		data, err := decodeData(response)
+		if err != nil {
+			e.apiErrors["appliance_healthcheck"]++
+			continue
+		}
+		heartbeat, _ := data.(map[string]interface{})
+
+		state, _ := heartbeat["nagiosOperatingState"].(string)
+		status, ok := healthStatus(state)
+		if !ok {
+			status = pluginUnknown
+		}
+		metrics.add("rtms_appliance_status", "gauge", "Nagios operating state of the last appliance heartbeat (0 OK, 1 WARNING, 2 CRITICAL, 3 UNKNOWN).", applianceLabels, float64(status))
+		if date, ok := heartbeatDate(heartbeat); ok {
+			metrics.add("rtms_appliance_heartbeat_age_seconds", "gauge", "Age of the last appliance heartbeat.", applianceLabels, time.Since(date).Seconds())
+		}
+	}
+}
+
+// flattenNumbers returns the numeric values of a decoded response, keyed by
+// their path. Nested keys are joined with an underscore.
+func flattenNumbers(v interface{}, path string) map[string]float64 {
+	values := make(map[string]float64)
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			if path != "" {
+				key = path + "_" + key
+			}
+			for k, n := range flattenNumbers(child, key) {
+				values[k] = n
+			}
+		}
+	case float64:
+		if path == "" {
+			path = "total"
+		}
+		values[path] = value
+	}
+	return values
+}
+
+func withLabel(labels map[string]string, name, value string) map[string]string {
+	result := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		result[k] = v
+	}
+	result[name] = value
+	return result
+}
+
+// metricsWriter renders metric families in the Prometheus text format.
+type metricsWriter struct {
+	order    []string
+	families map[string]*metricFamily
+}
+
+type metricFamily struct {
+	typ     string
+	help    string
+	samples []string
+}
+
+func newMetricsWriter() *metricsWriter {
+	return &metricsWriter{families: make(map[string]*metricFamily)}
+}
+
+func (w *metricsWriter) add(name, typ, help string, labels map[string]string, value float64) {
+	family, ok := w.families[name]
+	if !ok {
+		family = &metricFamily{typ: typ, help: help}
+		w.families[name] = family
+		w.order = append(w.order, name)
+	}
+
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	pairs := make([]string, 0, len(names))
+	for _, k := range names {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, k, escapeLabelValue(labels[k])))
+	}
+
+	sample := name
+	if len(pairs) > 0 {
+		sample += "{" + strings.Join(pairs, ",") + "}"
+	}
+	family.samples = append(family.samples, sample+" "+strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+func (w *metricsWriter) bytes() []byte {
+	var builder strings.Builder
+	for _, name := range w.order {
+		family := w.families[name]
+		sort.Strings(family.samples)
+		builder.WriteString(fmt.Sprintf("# HELP %s %s\n", name, family.help))
+		builder.WriteString(fmt.Sprintf("# TYPE %s %s\n", name, family.typ))
+		for _, sample := range family.samples {
+			builder.WriteString(sample + "\n")
+		}
+	}
+	return []byte(builder.String())
+}
+
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
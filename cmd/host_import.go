@@ -0,0 +1,286 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+)
+
+type hostImportRow struct {
+	Line       int
+	Name       string
+	Address    string
+	Tags       []string
+	Monitoring bool
+}
+
+func init() {
+	// Import hosts
+	importHostsCmd := &cobra.Command{
+		Use:   "import [file]",
+		Short: "Create hosts in bulk from a CSV or JSON file",
+		Long: `Create hosts in bulk from a CSV or JSON file.
+
+CSV files need a header line with the columns name, address and optionally tags
+(labels separated by ';') and monitoring (true/false). JSON files contain an
+array of objects with the same fields, tags being a list of labels.
+
+Hosts whose name already exists are skipped. Missing host tags are created.`,
+		Args: cobra.ExactArgs(1),
+		RunE: importHosts,
+	}
+	importHostsCmd.Flags().String("input-format", "", "Input file format (csv or json, guessed from the file extension by default)")
+	importHostsCmd.Flags().Bool("enable-monitoring", false, "Enable monitoring on created hosts unless the row says otherwise")
+	importHostsCmd.Flags().Int("concurrency", 4, "Number of hosts created in parallel")
+	importHostsCmd.Flags().String("report", "", "Write the result report to this file instead of the standard output")
+	hostsCmd.AddCommand(importHostsCmd)
+}
+
+func importHosts(cmd *cobra.Command, args []string) error {
+	inputFormat, _ := cmd.Flags().GetString("input-format")
+	enableMonitoring, _ := cmd.Flags().GetBool("enable-monitoring")
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	reportPath, _ := cmd.Flags().GetString("report")
+	format, _ := cmd.Flags().GetString("format")
+
+	if concurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1")
+	}
+
+	if inputFormat == "" {
+		inputFormat = strings.TrimPrefix(strings.ToLower(filepath.Ext(args[0])), ".")
+	}
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to open file: %v", err)
+	}
+	defer file.Close()
+
+	var rows []hostImportRow
+	switch inputFormat {
+	case "csv":
+		rows, err = parseHostImportCSV(file, enableMonitoring)
+	case "json":
+		rows, err = parseHostImportJSON(file, enableMonitoring)
+	default:
+		return fmt.Errorf("unsupported input format: %s. Supported formats are csv and json", inputFormat)
+	}
+	if err != nil {
+		return err
+	}
+
+	existingHosts, err := fetchAll("/hosts", map[string]string{"cloudTempleId": cloudTempleID})
+	if err != nil {
+		return fmt.Errorf("error fetching existing hosts: %w", err)
+	}
+	knownNames := make(map[string]bool)
+	for _, h := range existingHosts {
+		knownNames[strings.ToLower(resourceName(h))] = true
+	}
+
+	var labels []string
+	for _, row := range rows {
+		labels = append(labels, row.Tags...)
+	}
+	tagIDs, err := resolveHostTags(labels, true)
+	if err != nil {
+		return err
+	}
+
+	report := make([]interface{}, len(rows))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+
+	for i, row := range rows {
+		key := strings.ToLower(row.Name)
+		if knownNames[key] {
+			report[i] = hostImportResult(row, "skipped-existing", "", nil)
+			continue
+		}
+		knownNames[key] = true
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, row hostImportRow) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			id, err := importHost(row, tagIDs)
+			if err != nil {
+				report[i] = hostImportResult(row, "failed", id, err)
+				return
+			}
+			report[i] = hostImportResult(row, "created", id, nil)
+		}(i, row)
+	}
+	wg.Wait()
+
+	formattedOutput, err := formatOutput(report, format)
+	if err != nil {
+		return err
+	}
+
+	if reportPath != "" {
+		if err := ioutil.WriteFile(reportPath, []byte(formattedOutput+"\n"), 0644); err != nil {
+			return fmt.Errorf("failed to write report: %v", err)
+		}
+		fmt.Printf("Import report written to %s\n", reportPath)
+		return nil
+	}
+
+	fmt.Println(formattedOutput)
+	return nil
+}
+
+// importHost creates a single host, then applies its tags and monitoring. The
+// host ID is returned even when a later step fails.
+func importHost(row hostImportRow, tagIDs map[string]int) (string, error) {
+	response, err := client.CreateHost(cloudTempleID, map[string]interface{}{
+		"name":    row.Name,
+		"address": row.Address,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	host, err := decodeData(response)
+	if err != nil {
+		return "", err
+	}
+	id := resourceID(host)
+	if id == "" {
+		return "", fmt.Errorf("no host ID in API response")
+	}
+
+	if len(row.Tags) > 0 {
+		tags := make([]int, 0, len(row.Tags))
+		for _, label := range row.Tags {
+			tags = append(tags, tagIDs[strings.ToLower(label)])
+		}
+		if _, err := client.UpdateHostTags(id, tags); err != nil {
+			return id, fmt.Errorf("error updating tags: %w", err)
+		}
+	}
+
+	if row.Monitoring {
+		if _, err := client.SwitchHostMonitoring(id, true, nil); err != nil {
+			return id, fmt.Errorf("error enabling monitoring: %w", err)
+		}
+	}
+
+	return id, nil
+}
+
+func hostImportResult(row hostImportRow, status, id string, err error) map[string]interface{} {
+	result := map[string]interface{}{
+		"line":    row.Line,
+		"name":    row.Name,
+		"address": row.Address,
+		"status":  status,
+		"id":      id,
+		"error":   "",
+	}
+	if err != nil {
+		result["error"] = err.Error()
+	}
+	return result
+}
+
+func parseHostImportCSV(r io.Reader, defaultMonitoring bool) ([]hostImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error reading CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("CSV file is empty")
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"name", "address"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV header must contain a %q column", required)
+		}
+	}
+
+	field := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []hostImportRow
+	for i, record := range records[1:] {
+		row := hostImportRow{
+			Line:       i + 2,
+			Name:       field(record, "name"),
+			Address:    field(record, "address"),
+			Monitoring: defaultMonitoring,
+		}
+		for _, label := range strings.Split(field(record, "tags"), ";") {
+			if label = strings.TrimSpace(label); label != "" {
+				row.Tags = append(row.Tags, label)
+			}
+		}
+		if monitoring := field(record, "monitoring"); monitoring != "" {
+			row.Monitoring, err = strconv.ParseBool(monitoring)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid monitoring value %q", row.Line, monitoring)
+			}
+		}
+		if row.Name == "" || row.Address == "" {
+			return nil, fmt.Errorf("line %d: name and address are required", row.Line)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func parseHostImportJSON(r io.Reader, defaultMonitoring bool) ([]hostImportRow, error) {
+	var entries []struct {
+		Name       string   `json:"name"`
+		Address    string   `json:"address"`
+		Tags       []string `json:"tags"`
+		Monitoring *bool    `json:"monitoring"`
+	}
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+
+	rows := make([]hostImportRow, 0, len(entries))
+	for i, entry := range entries {
+		row := hostImportRow{
+			Line:       i + 1,
+			Name:       strings.TrimSpace(entry.Name),
+			Address:    strings.TrimSpace(entry.Address),
+			Tags:       entry.Tags,
+			Monitoring: defaultMonitoring,
+		}
+		if entry.Monitoring != nil {
+			row.Monitoring = *entry.Monitoring
+		}
+		if row.Name == "" || row.Address == "" {
+			return nil, fmt.Errorf("entry %d: name and address are required", row.Line)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
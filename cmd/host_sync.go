@@ -0,0 +1,470 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// inventoryHost is a machine read from an external source of truth, with the
+// host tag labels it should carry in RTMS.
+type inventoryHost struct {
+	Name    string
+	Address string
+	Tags    []string
+}
+
+func init() {
+	// Synchronize hosts
+	syncHostsCmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Synchronize hosts with an Ansible inventory or an nmap scan",
+		Long: `Synchronize RTMS hosts with an Ansible inventory (INI or YAML) or an nmap XML scan.
+
+Inventory hosts missing from RTMS are created, existing hosts get their address
+and tags updated. Ansible groups become host tags. Hosts that exist in RTMS but
+not in the inventory are reported and left untouched.`,
+		Args: cobra.NoArgs,
+		RunE: syncHosts,
+	}
+	syncHostsCmd.Flags().String("from-ansible", "", "Path of an Ansible inventory file (INI or YAML)")
+	syncHostsCmd.Flags().String("from-nmap", "", "Path of an nmap XML scan (nmap -oX)")
+	syncHostsCmd.Flags().StringSlice("tags", nil, "Additional host tag labels applied to every synchronized host")
+	syncHostsCmd.Flags().Bool("replace-tags", false, "Replace the host tags instead of adding the missing ones")
+	syncHostsCmd.Flags().Bool("dry-run", false, "Show the changes without applying them")
+	syncHostsCmd.RegisterFlagCompletionFunc("tags", completeNames("/hosts/tags"))
+	hostsCmd.AddCommand(syncHostsCmd)
+}
+
+func syncHosts(cmd *cobra.Command, args []string) error {
+	fromAnsible, _ := cmd.Flags().GetString("from-ansible")
+	fromNmap, _ := cmd.Flags().GetString("from-nmap")
+	extraTags, _ := cmd.Flags().GetStringSlice("tags")
+	replaceTags, _ := cmd.Flags().GetBool("replace-tags")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	format, _ := cmd.Flags().GetString("format")
+
+	var inventory []inventoryHost
+	var err error
+	switch {
+	case fromAnsible != "" && fromNmap != "":
+		return fmt.Errorf("--from-ansible and --from-nmap cannot be used together")
+	case fromAnsible != "":
+		inventory, err = readAnsibleInventory(fromAnsible)
+	case fromNmap != "":
+		inventory, err = readNmapScan(fromNmap)
+	default:
+		return fmt.Errorf("one of --from-ansible or --from-nmap is required")
+	}
+	if err != nil {
+		return err
+	}
+
+	var labels []string
+	for i := range inventory {
+		inventory[i].Tags = append(inventory[i].Tags, extraTags...)
+		labels = append(labels, inventory[i].Tags...)
+	}
+
+	tagIDs, err := resolveHostTags(labels, !dryRun)
+	if err != nil {
+		return err
+	}
+
+	existingHosts, err := fetchAll("/hosts", map[string]string{"cloudTempleId": cloudTempleID})
+	if err != nil {
+		return fmt.Errorf("error fetching existing hosts: %w", err)
+	}
+	hostsByName := make(map[string]map[string]interface{})
+	for _, h := range existingHosts {
+		if m, ok := h.(map[string]interface{}); ok {
+			hostsByName[strings.ToLower(resourceName(m))] = m
+		}
+	}
+
+	var report []interface{}
+	seen := make(map[string]bool)
+	for _, inv := range inventory {
+		key := strings.ToLower(inv.Name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		action, id, err := syncHost(inv, hostsByName[key], tagIDs, replaceTags, dryRun)
+		row := map[string]interface{}{
+			"name":    inv.Name,
+			"address": inv.Address,
+			"tags":    strings.Join(inv.Tags, ","),
+			"action":  action,
+			"id":      id,
+			"error":   "",
+		}
+		if err != nil {
+			row["action"] = "failed"
+			row["error"] = err.Error()
+		}
+		report = append(report, row)
+	}
+
+	var missing []string
+	for key := range hostsByName {
+		if !seen[key] {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+	for _, key := range missing {
+		h := hostsByName[key]
+		address, _ := h["address"].(string)
+		report = append(report, map[string]interface{}{
+			"name":    resourceName(h),
+			"address": address,
+			"tags":    "",
+			"action":  "missing-from-inventory",
+			"id":      resourceID(h),
+			"error":   "",
+		})
+	}
+
+	formattedOutput, err := formatOutput(report, format)
+	if err != nil {
+		return err
+	}
+	fmt.Println(formattedOutput)
+	return nil
+}
+
+// syncHost creates or updates a single RTMS host so it matches the inventory
+// entry, and returns the action taken.
+func syncHost(inv inventoryHost, existing map[string]interface{}, tagIDs map[string]int, replaceTags, dryRun bool) (string, string, error) {
+	// Labels without an ID only happen in dry-run mode, where missing tags
+	// are not created.
+	wanted := make([]int, 0, len(inv.Tags))
+	missingTags := false
+	for _, label := range inv.Tags {
+		if id, ok := tagIDs[strings.ToLower(label)]; ok {
+			wanted = append(wanted, id)
+		} else {
+			missingTags = true
+		}
+	}
+
+	if existing == nil {
+		if dryRun {
+			return "create", "", nil
+		}
+		id, err := importHost(hostImportRow{Name: inv.Name, Address: inv.Address, Tags: inv.Tags}, tagIDs)
+		return "created", id, err
+	}
+
+	id := resourceID(existing)
+	changed := missingTags
+
+	if address, _ := existing["address"].(string); inv.Address != "" && address != inv.Address {
+		changed = true
+		if !dryRun {
+			if _, err := client.UpdateHost(id, map[string]interface{}{"address": inv.Address}); err != nil {
+				return "", id, fmt.Errorf("error updating address: %w", err)
+			}
+		}
+	}
+
+	current := hostTagIDs(existing)
+	tags := wanted
+	if !replaceTags {
+		tags = mergeIDs(current, wanted)
+	}
+	if !sameIDs(current, tags) {
+		changed = true
+		if !dryRun {
+			if _, err := client.UpdateHostTags(id, tags); err != nil {
+				return "", id, fmt.Errorf("error updating tags: %w", err)
+			}
+		}
+	}
+
+	switch {
+	case !changed:
+		return "unchanged", id, nil
+	case dryRun:
+		return "update", id, nil
+	default:
+		return "updated", id, nil
+	}
+}
+
+// hostTagIDs extracts the tag IDs of a decoded host, whether the API returns
+// them as plain IDs or as tag objects.
+func hostTagIDs(host map[string]interface{}) []int {
+	var ids []int
+	tags, _ := host["tags"].([]interface{})
+	for _, tag := range tags {
+		switch t := tag.(type) {
+		case float64:
+			ids = append(ids, int(t))
+		case map[string]interface{}:
+			if id, err := strconv.Atoi(resourceID(t)); err == nil {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+func mergeIDs(a, b []int) []int {
+	merged := append([]int{}, a...)
+	for _, id := range b {
+		found := false
+		for _, existing := range merged {
+			if existing == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, id)
+		}
+	}
+	return merged
+}
+
+func sameIDs(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sortedA := append([]int{}, a...)
+	sortedB := append([]int{}, b...)
+	sort.Ints(sortedA)
+	sort.Ints(sortedB)
+	for i := range sortedA {
+		if sortedA[i] != sortedB[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ansibleInventory accumulates hosts and group relations while an inventory is
+// being parsed.
+type ansibleInventory struct {
+	order     []string
+	addresses map[string]string
+	groups    map[string][]string
+	parents   map[string][]string
+}
+
+func newAnsibleInventory() *ansibleInventory {
+	return &ansibleInventory{
+		addresses: make(map[string]string),
+		groups:    make(map[string][]string),
+		parents:   make(map[string][]string),
+	}
+}
+
+func (inv *ansibleInventory) addHost(name, group, address string) {
+	if _, ok := inv.addresses[name]; !ok {
+		inv.order = append(inv.order, name)
+		inv.addresses[name] = ""
+	}
+	if address != "" {
+		inv.addresses[name] = address
+	}
+	if group != "" {
+		inv.groups[name] = append(inv.groups[name], group)
+	}
+}
+
+func (inv *ansibleInventory) addChild(parent, child string) {
+	inv.parents[child] = append(inv.parents[child], parent)
+}
+
+// hosts resolves every host with the tags of its groups and their ancestors.
+// The implicit "all" and "ungrouped" groups do not become tags.
+func (inv *ansibleInventory) hosts() []inventoryHost {
+	var result []inventoryHost
+	for _, name := range inv.order {
+		tags := make(map[string]bool)
+		var visit func(group string)
+		visit = func(group string) {
+			if tags[group] {
+				return
+			}
+			tags[group] = true
+			for _, parent := range inv.parents[group] {
+				visit(parent)
+			}
+		}
+		for _, group := range inv.groups[name] {
+			visit(group)
+		}
+		delete(tags, "all")
+		delete(tags, "ungrouped")
+
+		host := inventoryHost{Name: name, Address: inv.addresses[name]}
+		for tag := range tags {
+			host.Tags = append(host.Tags, tag)
+		}
+		sort.Strings(host.Tags)
+		if host.Address == "" {
+			host.Address = name
+		}
+		result = append(result, host)
+	}
+	return result
+}
+
+func readAnsibleInventory(path string) ([]inventoryHost, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inventory: %v", err)
+	}
+
+	inv := newAnsibleInventory()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yml", ".yaml":
+		var root map[string]interface{}
+		if err := yaml.Unmarshal(content, &root); err != nil {
+			return nil, fmt.Errorf("invalid YAML inventory: %v", err)
+		}
+		for group, definition := range root {
+			parseAnsibleYAMLGroup(inv, group, definition)
+		}
+	default:
+		if err := parseAnsibleINI(inv, string(content)); err != nil {
+			return nil, err
+		}
+	}
+
+	return inv.hosts(), nil
+}
+
+func parseAnsibleYAMLGroup(inv *ansibleInventory, group string, definition interface{}) {
+	def, ok := definition.(map[interface{}]interface{})
+	if !ok {
+		return
+	}
+
+	if hosts, ok := def["hosts"].(map[interface{}]interface{}); ok {
+		for name, vars := range hosts {
+			address := ""
+			if v, ok := vars.(map[interface{}]interface{}); ok && v["ansible_host"] != nil {
+				address = fmt.Sprint(v["ansible_host"])
+			}
+			inv.addHost(fmt.Sprint(name), group, address)
+		}
+	}
+
+	if children, ok := def["children"].(map[interface{}]interface{}); ok {
+		for child, childDefinition := range children {
+			inv.addChild(group, fmt.Sprint(child))
+			parseAnsibleYAMLGroup(inv, fmt.Sprint(child), childDefinition)
+		}
+	}
+}
+
+func parseAnsibleINI(inv *ansibleInventory, content string) error {
+	group := "ungrouped"
+	section := "hosts"
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return fmt.Errorf("inventory line %d: invalid section header", lineNumber)
+			}
+			header := strings.Trim(line, "[]")
+			group, section = header, "hosts"
+			if i := strings.Index(header, ":"); i >= 0 {
+				group, section = header[:i], header[i+1:]
+			}
+			continue
+		}
+
+		fields := strings.Fields(line)
+		switch section {
+		case "hosts":
+			address := ""
+			for _, field := range fields[1:] {
+				if strings.HasPrefix(field, "ansible_host=") {
+					address = strings.TrimPrefix(field, "ansible_host=")
+				}
+			}
+			inv.addHost(fields[0], group, address)
+		case "children":
+			inv.addChild(group, fields[0])
+		}
+	}
+
+	return scanner.Err()
+}
+
+type nmapRun struct {
+	Hosts []struct {
+		Status struct {
+			State string `xml:"state,attr"`
+		} `xml:"status"`
+		Addresses []struct {
+			Addr     string `xml:"addr,attr"`
+			AddrType string `xml:"addrtype,attr"`
+		} `xml:"address"`
+		Hostnames []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"hostnames>hostname"`
+	} `xml:"host"`
+}
+
+func readNmapScan(path string) ([]inventoryHost, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open scan: %v", err)
+	}
+	defer file.Close()
+
+	var run nmapRun
+	if err := xml.NewDecoder(file).Decode(&run); err != nil {
+		return nil, fmt.Errorf("invalid nmap XML: %v", err)
+	}
+
+	var hosts []inventoryHost
+	for _, h := range run.Hosts {
+		if h.Status.State != "" && h.Status.State != "up" {
+			continue
+		}
+
+		host := inventoryHost{}
+		for _, address := range h.Addresses {
+			if address.AddrType == "ipv4" || address.AddrType == "ipv6" {
+				host.Address = address.Addr
+				break
+			}
+		}
+		if host.Address == "" {
+			continue
+		}
+
+		host.Name = host.Address
+		if len(h.Hostnames) > 0 && h.Hostnames[0].Name != "" {
+			host.Name = h.Hostnames[0].Name
+		}
+		hosts = append(hosts, host)
+	}
+
+	return hosts, nil
+}
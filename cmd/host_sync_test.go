@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseAnsibleINI(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []inventoryHost
+		wantErr bool
+	}{
+		{
+			name: "ungrouped hosts and comments",
+			content: `# inventory
+; another comment
+web01 ansible_host=10.0.0.1
+
+db01
+`,
+			want: []inventoryHost{
+				{Name: "web01", Address: "10.0.0.1"},
+				{Name: "db01", Address: "db01"},
+			},
+		},
+		{
+			name: "groups and children",
+			content: `[web]
+web01 ansible_host=10.0.0.1 ansible_user=deploy
+web02
+
+[db]
+db01 ansible_host=10.0.1.1
+
+[prod:children]
+web
+db
+
+[europe:children]
+prod
+
+[web:vars]
+http_port=80
+`,
+			want: []inventoryHost{
+				{Name: "web01", Address: "10.0.0.1", Tags: []string{"europe", "prod", "web"}},
+				{Name: "web02", Address: "web02", Tags: []string{"europe", "prod", "web"}},
+				{Name: "db01", Address: "10.0.1.1", Tags: []string{"db", "europe", "prod"}},
+			},
+		},
+		{
+			name: "host in several groups",
+			content: `[web]
+app01
+[monitoring]
+app01 ansible_host=10.0.2.1
+[all:children]
+web
+`,
+			want: []inventoryHost{
+				{Name: "app01", Address: "10.0.2.1", Tags: []string{"monitoring", "web"}},
+			},
+		},
+		{
+			name:    "unterminated section",
+			content: "[web\nweb01\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := newAnsibleInventory()
+			err := parseAnsibleINI(inv, tt.content)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseAnsibleINI() returned no error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseAnsibleINI() returned %v", err)
+			}
+			if got := inv.hosts(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("hosts() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadAnsibleYAMLInventory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inventory.yml")
+	content := `all:
+  children:
+    web:
+      hosts:
+        web01:
+          ansible_host: 10.0.0.1
+    prod:
+      children:
+        web:
+`
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readAnsibleInventory(path)
+	if err != nil {
+		t.Fatalf("readAnsibleInventory() returned %v", err)
+	}
+	want := []inventoryHost{{Name: "web01", Address: "10.0.0.1", Tags: []string{"prod", "web"}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readAnsibleInventory() = %+v, want %+v", got, want)
+	}
+}
+
+func TestReadNmapScan(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []inventoryHost
+		wantErr bool
+	}{
+		{
+			name: "hosts up with and without names",
+			content: `<?xml version="1.0"?>
+<nmaprun>
+  <host>
+    <status state="up"/>
+    <address addr="00:11:22:33:44:55" addrtype="mac"/>
+    <address addr="10.0.0.1" addrtype="ipv4"/>
+    <hostnames><hostname name="web01.example.com" type="PTR"/></hostnames>
+  </host>
+  <host>
+    <status state="down"/>
+    <address addr="10.0.0.2" addrtype="ipv4"/>
+  </host>
+  <host>
+    <status state="up"/>
+    <address addr="fe80::1" addrtype="ipv6"/>
+  </host>
+  <host>
+    <status state="up"/>
+    <address addr="00:11:22:33:44:66" addrtype="mac"/>
+  </host>
+</nmaprun>
+`,
+			want: []inventoryHost{
+				{Name: "web01.example.com", Address: "10.0.0.1"},
+				{Name: "fe80::1", Address: "fe80::1"},
+			},
+		},
+		{
+			name:    "not XML",
+			content: "Nmap scan report for 10.0.0.1",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "scan.xml")
+			if err := ioutil.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := readNmapScan(path)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("readNmapScan() returned no error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readNmapScan() returned %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readNmapScan() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -32,46 +32,52 @@ func init() {
 	}
 	createHostTagCmd.Flags().String("label", "", "Tag label")
 	createHostTagCmd.Flags().String("description", "", "Tag description")
-	createHostTagCmd.Flags().IntSlice("hosts", nil, "List of host IDs to associate with the tag")
+	createHostTagCmd.Flags().StringSlice("hosts", nil, "List of host IDs or names to associate with the tag")
 	createHostTagCmd.MarkFlagRequired("label")
+	createHostTagCmd.RegisterFlagCompletionFunc("hosts", completeNames("/hosts"))
 	hostTagsCmd.AddCommand(createHostTagCmd)
 
 	// Get host tag details
 	getHostTagDetailsCmd := &cobra.Command{
-		Use:   "details [id]",
-		Short: "Get details of a tag",
-		Args:  cobra.ExactArgs(1),
-		RunE:  getHostTagDetails,
+		Use:               "details [id]",
+		Short:             "Get details of a tag",
+		Args:              cobra.ExactArgs(1),
+		RunE:              getHostTagDetails,
+		ValidArgsFunction: completeIDs("/hosts/tags"),
 	}
 	hostTagsCmd.AddCommand(getHostTagDetailsCmd)
 
 	// Remove host tag
 	removeHostTagCmd := &cobra.Command{
-		Use:   "remove [id]",
-		Short: "Remove a host tag",
-		Args:  cobra.ExactArgs(1),
-		RunE:  removeHostTag,
+		Use:               "remove [id]",
+		Short:             "Remove a host tag",
+		Args:              cobra.ExactArgs(1),
+		RunE:              removeHostTag,
+		ValidArgsFunction: completeIDs("/hosts/tags"),
 	}
 	hostTagsCmd.AddCommand(removeHostTagCmd)
 
 	// Edit host tag
 	editHostTagCmd := &cobra.Command{
-		Use:   "edit [id]",
-		Short: "Edit host tag",
-		Args:  cobra.ExactArgs(1),
-		RunE:  editHostTag,
+		Use:               "edit [id]",
+		Short:             "Edit host tag",
+		Args:              cobra.ExactArgs(1),
+		RunE:              editHostTag,
+		ValidArgsFunction: completeIDs("/hosts/tags"),
 	}
 	editHostTagCmd.Flags().String("label", "", "Tag label")
 	editHostTagCmd.Flags().String("description", "", "Tag description")
-	editHostTagCmd.Flags().IntSlice("hosts", nil, "List of host IDs to associate with the tag")
+	editHostTagCmd.Flags().StringSlice("hosts", nil, "List of host IDs or names to associate with the tag")
+	editHostTagCmd.RegisterFlagCompletionFunc("hosts", completeNames("/hosts"))
 	hostTagsCmd.AddCommand(editHostTagCmd)
 
 	// Get hosts by tag
 	getHostsByTagCmd := &cobra.Command{
-		Use:   "hosts [id]",
-		Short: "Gets hosts that match a given tag",
-		Args:  cobra.ExactArgs(1),
-		RunE:  getHostsByTag,
+		Use:               "hosts [id]",
+		Short:             "Gets hosts that match a given tag",
+		Args:              cobra.ExactArgs(1),
+		RunE:              getHostsByTag,
+		ValidArgsFunction: completeIDs("/hosts/tags"),
 	}
 	hostTagsCmd.AddCommand(getHostsByTagCmd)
 }
@@ -100,7 +106,7 @@ func getHostTags(cmd *cobra.Command, args []string) error {
 func createHostTag(cmd *cobra.Command, args []string) error {
 	label, _ := cmd.Flags().GetString("label")
 	description, _ := cmd.Flags().GetString("description")
-	hosts, _ := cmd.Flags().GetIntSlice("hosts")
+	hostRefs, _ := cmd.Flags().GetStringSlice("hosts")
 	format, _ := cmd.Flags().GetString("format")
 
 	tagData := map[string]interface{}{
@@ -109,7 +115,11 @@ func createHostTag(cmd *cobra.Command, args []string) error {
 	if description != "" {
 		tagData["description"] = description
 	}
-	if len(hosts) > 0 {
+	if len(hostRefs) > 0 {
+		hosts, err := resolveHostIDs(hostRefs)
+		if err != nil {
+			return err
+		}
 		tagData["hosts"] = hosts
 	}
 
@@ -143,6 +153,14 @@ func getHostTagDetails(cmd *cobra.Command, args []string) error {
 
 func removeHostTag(cmd *cobra.Command, args []string) error {
 	format, _ := cmd.Flags().GetString("format")
+	confirmed, err := confirmDeletion("host-tag", args[0], client.GetHostTagDetails)
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		fmt.Println("Operation cancelled.")
+		return nil
+	}
 	response, err := client.RemoveHostTag(args[0])
 	if err != nil {
 		return err
@@ -159,7 +177,7 @@ func removeHostTag(cmd *cobra.Command, args []string) error {
 func editHostTag(cmd *cobra.Command, args []string) error {
 	label, _ := cmd.Flags().GetString("label")
 	description, _ := cmd.Flags().GetString("description")
-	hosts, _ := cmd.Flags().GetIntSlice("hosts")
+	hostRefs, _ := cmd.Flags().GetStringSlice("hosts")
 	format, _ := cmd.Flags().GetString("format")
 
 	tagData := make(map[string]interface{})
@@ -169,7 +187,11 @@ func editHostTag(cmd *cobra.Command, args []string) error {
 	if description != "" {
 		tagData["description"] = description
 	}
-	if len(hosts) > 0 {
+	if len(hostRefs) > 0 {
+		hosts, err := resolveHostIDs(hostRefs)
+		if err != nil {
+			return err
+		}
 		tagData["hosts"] = hosts
 	}
 
@@ -199,4 +221,4 @@ func getHostsByTag(cmd *cobra.Command, args []string) error {
 
 	fmt.Println(formattedOutput)
 	return nil
-}
\ No newline at end of file
+}
@@ -64,28 +64,31 @@ func init() {
 
 	// Get host details
 	getHostDetailsCmd := &cobra.Command{
-		Use:   "details [id]",
-		Short: "Get Host details",
-		Args:  cobra.ExactArgs(1),
-		RunE:  getHostDetails,
+		Use:               "details [id]",
+		Short:             "Get Host details",
+		Args:              cobra.ExactArgs(1),
+		RunE:              getHostDetails,
+		ValidArgsFunction: completeIDs("/hosts"),
 	}
 	hostsCmd.AddCommand(getHostDetailsCmd)
 
 	// Remove host
 	removeHostCmd := &cobra.Command{
-		Use:   "remove [id]",
-		Short: "Remove Host",
-		Args:  cobra.ExactArgs(1),
-		RunE:  removeHost,
+		Use:               "remove [id]",
+		Short:             "Remove Host",
+		Args:              cobra.ExactArgs(1),
+		RunE:              removeHost,
+		ValidArgsFunction: completeIDs("/hosts"),
 	}
 	hostsCmd.AddCommand(removeHostCmd)
 
 	// Update host
 	updateHostCmd := &cobra.Command{
-		Use:   "update [id]",
-		Short: "Update a Host",
-		Args:  cobra.ExactArgs(1),
-		RunE:  updateHost,
+		Use:               "update [id]",
+		Short:             "Update a Host",
+		Args:              cobra.ExactArgs(1),
+		RunE:              updateHost,
+		ValidArgsFunction: completeIDs("/hosts"),
 	}
 	updateHostCmd.Flags().String("name", "", "Host name")
 	updateHostCmd.Flags().String("address", "", "Host monitoring ip address")
@@ -93,30 +96,34 @@ func init() {
 
 	// Get host services
 	getHostServicesCmd := &cobra.Command{
-		Use:   "services [id]",
-		Short: "Get Host services",
-		Args:  cobra.ExactArgs(1),
-		RunE:  getHostServices,
+		Use:               "services [id]",
+		Short:             "Get Host services",
+		Args:              cobra.ExactArgs(1),
+		RunE:              getHostServices,
+		ValidArgsFunction: completeIDs("/hosts"),
 	}
 	hostsCmd.AddCommand(getHostServicesCmd)
 
 	// Update host tags
 	updateHostTagsCmd := &cobra.Command{
-		Use:   "update-tags [id]",
-		Short: "Update Host tags",
-		Args:  cobra.ExactArgs(1),
-		RunE:  updateHostTags,
+		Use:               "update-tags [id]",
+		Short:             "Update Host tags",
+		Args:              cobra.ExactArgs(1),
+		RunE:              updateHostTags,
+		ValidArgsFunction: completeIDs("/hosts"),
 	}
-	updateHostTagsCmd.Flags().IntSlice("tags", nil, "List of tag IDs")
+	updateHostTagsCmd.Flags().StringSlice("tags", nil, "List of tag IDs or labels")
 	updateHostTagsCmd.MarkFlagRequired("tags")
+	updateHostTagsCmd.RegisterFlagCompletionFunc("tags", completeNames("/hosts/tags"))
 	hostsCmd.AddCommand(updateHostTagsCmd)
 
 	// Switch host monitoring
 	switchHostMonitoringCmd := &cobra.Command{
-		Use:   "switch-monitoring [id]",
-		Short: "Disable/enable monitoring for all or specific host's services",
-		Args:  cobra.ExactArgs(1),
-		RunE:  switchHostMonitoring,
+		Use:               "switch-monitoring [id]",
+		Short:             "Disable/enable monitoring for all or specific host's services",
+		Args:              cobra.ExactArgs(1),
+		RunE:              switchHostMonitoring,
+		ValidArgsFunction: completeIDs("/hosts"),
 	}
 	switchHostMonitoringCmd.Flags().Bool("enable", false, "Enable or disable monitoring")
 	switchHostMonitoringCmd.Flags().IntSlice("services", nil, "List of service IDs")
@@ -125,10 +132,11 @@ func init() {
 
 	// Switch host monitoring notifications
 	switchHostMonitoringNotificationsCmd := &cobra.Command{
-		Use:   "switch-notifications [id]",
-		Short: "Disable/enable monitoring notifications for all or specific host's services",
-		Args:  cobra.ExactArgs(1),
-		RunE:  switchHostMonitoringNotifications,
+		Use:               "switch-notifications [id]",
+		Short:             "Disable/enable monitoring notifications for all or specific host's services",
+		Args:              cobra.ExactArgs(1),
+		RunE:              switchHostMonitoringNotifications,
+		ValidArgsFunction: completeIDs("/hosts"),
 	}
 	switchHostMonitoringNotificationsCmd.Flags().Bool("enable", false, "Enable or disable notifications")
 	switchHostMonitoringNotificationsCmd.Flags().IntSlice("services", nil, "List of service IDs")
@@ -141,6 +149,7 @@ func init() {
 		Short: "Get hosts status stats",
 		RunE:  getHostsStats,
 	}
+	addWatchFlag(getHostsStatsCmd)
 	hostsCmd.AddCommand(getHostsStatsCmd)
 }
 
@@ -247,6 +256,14 @@ func getHostDetails(cmd *cobra.Command, args []string) error {
 
 func removeHost(cmd *cobra.Command, args []string) error {
 	format, _ := cmd.Flags().GetString("format")
+	confirmed, err := confirmDeletion("host", args[0], client.GetHostDetails)
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		fmt.Println("Operation cancelled.")
+		return nil
+	}
 	response, err := client.RemoveHost(args[0])
 	if err != nil {
 		return err
@@ -308,8 +325,12 @@ func getHostServices(cmd *cobra.Command, args []string) error {
 }
 
 func updateHostTags(cmd *cobra.Command, args []string) error {
-	tags, _ := cmd.Flags().GetIntSlice("tags")
+	tagRefs, _ := cmd.Flags().GetStringSlice("tags")
 	format, _ := cmd.Flags().GetString("format")
+	tags, err := resolveHostTagIDs(tagRefs)
+	if err != nil {
+		return err
+	}
 	response, err := client.UpdateHostTags(args[0], tags)
 	if err != nil {
 		return err
@@ -365,6 +386,20 @@ func switchHostMonitoringNotifications(cmd *cobra.Command, args []string) error
 
 func getHostsStats(cmd *cobra.Command, args []string) error {
 	format, _ := cmd.Flags().GetString("format")
+	interval, err := watchInterval(cmd)
+	if err != nil {
+		return err
+	}
+	if interval > 0 {
+		return runWatch(cmd, interval, func() (interface{}, error) {
+			response, err := client.GetHostsStats(cloudTempleID)
+			if err != nil {
+				return nil, err
+			}
+			return decodeData(response)
+		})
+	}
+
 	response, err := client.GetHostsStats(cloudTempleID)
 	if err != nil {
 		return err
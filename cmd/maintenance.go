@@ -0,0 +1,327 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const maintenanceStateFile = "maintenance.json"
+
+// maintenanceWindow records a maintenance started from the CLI, so that
+// notifications can be switched off when it starts and restored when it ends.
+type maintenanceWindow struct {
+	StopID string            `json:"stopId"`
+	Reason string            `json:"reason"`
+	Start  time.Time         `json:"start"`
+	End    time.Time         `json:"end"`
+	Hosts  []maintenanceHost `json:"hosts"`
+	// NotificationsDisabled is true when the notifications of the hosts are
+	// switched off during the window
+	NotificationsDisabled bool `json:"notificationsDisabled"`
+}
+
+type maintenanceHost struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Notified is true when notifications were enabled before the window.
+	Notified bool `json:"notified"`
+	// Disabled is true while the notifications are switched off by the
+	// window, and have to be switched back on when it ends.
+	Disabled bool `json:"disabled"`
+}
+
+var maintenanceCmd = &cobra.Command{
+	Use:   "maintenance",
+	Short: "Manage maintenance windows",
+	Long:  `Manage maintenance windows, built on notification time period stops. Notifications of the hosts can be disabled during the window and restored when it ends.`,
+}
+
+func init() {
+	rootCmd.AddCommand(maintenanceCmd)
+
+	// Start maintenance
+	startMaintenanceCmd := &cobra.Command{
+		Use:   "start",
+		Short: "Start a maintenance window on hosts",
+		RunE:  startMaintenance,
+	}
+	startMaintenanceCmd.Flags().StringSlice("hosts", nil, "Host names or IDs")
+	startMaintenanceCmd.Flags().String("duration", "", "Duration of the window (e.g. 30m, 2h, 1d)")
+	startMaintenanceCmd.Flags().String("start", "", "Start date in RFC3339 format (default: now)")
+	startMaintenanceCmd.Flags().String("reason", "", "Reason of the maintenance")
+	startMaintenanceCmd.Flags().Bool("disable-notifications", false, "Also disable host notifications during the window")
+	startMaintenanceCmd.MarkFlagRequired("hosts")
+	startMaintenanceCmd.MarkFlagRequired("duration")
+	startMaintenanceCmd.MarkFlagRequired("reason")
+	startMaintenanceCmd.RegisterFlagCompletionFunc("hosts", completeNames("/hosts"))
+	maintenanceCmd.AddCommand(startMaintenanceCmd)
+
+	// List maintenance windows
+	listMaintenanceCmd := &cobra.Command{
+		Use:   "list",
+		Short: "Get a list of notification time period stops",
+	}
+	updateListCommand(listMaintenanceCmd, "/monitoringServices/notifications/timePeriodStops", func() map[string]string {
+		return map[string]string{
+			"cloudTempleId": cloudTempleID,
+		}
+	})
+	listMaintenanceStops := listMaintenanceCmd.RunE
+	listMaintenanceCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if err := updateMaintenanceWindows(""); err != nil {
+			return err
+		}
+		return listMaintenanceStops(cmd, args)
+	}
+	maintenanceCmd.AddCommand(listMaintenanceCmd)
+
+	// End maintenance
+	endMaintenanceCmd := &cobra.Command{
+		Use:   "end [stop-id]",
+		Short: "End a maintenance window and restore notifications",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  endMaintenance,
+	}
+	endMaintenanceCmd.Flags().Bool("expired", false, "End every window started from this CLI whose end date has passed")
+	maintenanceCmd.AddCommand(endMaintenanceCmd)
+}
+
+func startMaintenance(cmd *cobra.Command, args []string) error {
+	hostRefs, _ := cmd.Flags().GetStringSlice("hosts")
+	durationFlag, _ := cmd.Flags().GetString("duration")
+	startFlag, _ := cmd.Flags().GetString("start")
+	reason, _ := cmd.Flags().GetString("reason")
+	disableNotifications, _ := cmd.Flags().GetBool("disable-notifications")
+	format, _ := cmd.Flags().GetString("format")
+
+	duration, err := parseDuration(durationFlag)
+	if err != nil {
+		return err
+	}
+	if duration <= 0 {
+		return fmt.Errorf("duration must be positive")
+	}
+
+	start := time.Now()
+	if startFlag != "" {
+		start, err = time.Parse(time.RFC3339, startFlag)
+		if err != nil {
+			return fmt.Errorf("invalid start date: %v", err)
+		}
+	}
+
+	if err := updateMaintenanceWindows(""); err != nil {
+		return err
+	}
+	hosts, err := resolveHosts(hostRefs)
+	if err != nil {
+		return err
+	}
+
+	window := maintenanceWindow{
+		Reason:                reason,
+		Start:                 start,
+		End:                   start.Add(duration),
+		NotificationsDisabled: disableNotifications,
+	}
+	hostIDs := make([]int, 0, len(hosts))
+	for _, h := range hosts {
+		id, _ := strconv.Atoi(resourceID(h))
+		hostIDs = append(hostIDs, id)
+		window.Hosts = append(window.Hosts, maintenanceHost{
+			ID:       resourceID(h),
+			Name:     resourceName(h),
+			Notified: hostNotificationsEnabled(h),
+		})
+	}
+
+	stopData := map[string]interface{}{
+		"name":        fmt.Sprintf("Maintenance: %s", reason),
+		"description": reason,
+		"startDate":   window.Start.Unix(),
+		"endDate":     window.End.Unix(),
+		"hosts":       hostIDs,
+	}
+
+	response, err := client.CreateNotificationTimePeriodStop(cloudTempleID, stopData)
+	if err != nil {
+		return err
+	}
+	stop, err := decodeData(response)
+	if err != nil {
+		return err
+	}
+	window.StopID = resourceID(stop)
+	if window.StopID == "" {
+		// The window could not be ended, nor told apart from the others
+		return fmt.Errorf("time period stop created, but its ID is missing from the response: notifications were not disabled")
+	}
+
+	// The window is saved before any host is switched, so that it can be
+	// ended even when switching a host fails
+	var windows []maintenanceWindow
+	if err := loadState(maintenanceStateFile, &windows); err != nil {
+		return err
+	}
+	windows = append(windows, window)
+	save := func() error {
+		return saveState(maintenanceStateFile, windows)
+	}
+	if err := save(); err != nil {
+		return err
+	}
+	if !window.Start.After(time.Now()) {
+		if err := disableMaintenanceNotifications(&windows[len(windows)-1], save); err != nil {
+			return err
+		}
+	}
+
+	formattedOutput, err := formatOutput(response, format)
+	if err != nil {
+		return err
+	}
+	fmt.Println(formattedOutput)
+	return nil
+}
+
+func endMaintenance(cmd *cobra.Command, args []string) error {
+	expired, _ := cmd.Flags().GetBool("expired")
+
+	if (len(args) == 1) == expired {
+		return fmt.Errorf("either a time period stop ID or --expired is required")
+	}
+
+	// Expired windows are ended by updateMaintenanceWindows
+	stopID := ""
+	if !expired {
+		stopID = args[0]
+	}
+	if err := updateMaintenanceWindows(stopID); err != nil || expired {
+		return err
+	}
+
+	var windows []maintenanceWindow
+	if err := loadState(maintenanceStateFile, &windows); err != nil {
+		return err
+	}
+	save := func() error {
+		return saveState(maintenanceStateFile, windows)
+	}
+	for i := range windows {
+		if windows[i].StopID != args[0] {
+			continue
+		}
+		window := &windows[i]
+		if err := restoreMaintenanceNotifications(window, save); err != nil {
+			return err
+		}
+		if _, err := client.RemoveNotificationTimePeriodStop(window.StopID); err != nil {
+			return err
+		}
+		fmt.Printf("Maintenance %s ended (%s)\n", window.StopID, window.Reason)
+		windows = append(windows[:i], windows[i+1:]...)
+		return save()
+	}
+
+	// Windows created outside of this CLI are only known by the API
+	if _, err := client.RemoveNotificationTimePeriodStop(args[0]); err != nil {
+		return err
+	}
+	fmt.Printf("Maintenance %s ended\n", args[0])
+	return nil
+}
+
+// updateMaintenanceWindows switches off the notifications of the windows that
+// started and restores those of the windows that ended, as the CLI does not
+// run at these times. It is run by every maintenance command, and skips the
+// window of the stop being ended. Expired time period stops are left to the
+// API.
+func updateMaintenanceWindows(endingStopID string) error {
+	var windows []maintenanceWindow
+	if err := loadState(maintenanceStateFile, &windows); err != nil {
+		return err
+	}
+	save := func() error {
+		return saveState(maintenanceStateFile, windows)
+	}
+
+	now := time.Now()
+	for i := 0; i < len(windows); i++ {
+		window := &windows[i]
+		if window.Start.After(now) || window.StopID == endingStopID {
+			continue
+		}
+		if window.End.After(now) {
+			if err := disableMaintenanceNotifications(window, save); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := restoreMaintenanceNotifications(window, save); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Maintenance %s ended (%s)\n", window.StopID, window.Reason)
+		windows = append(windows[:i], windows[i+1:]...)
+		i--
+		if err := save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// disableMaintenanceNotifications switches off the notifications of the hosts
+// of a window that asks for it, saving the state after each host.
+func disableMaintenanceNotifications(window *maintenanceWindow, save func() error) error {
+	if !window.NotificationsDisabled {
+		return nil
+	}
+	for i := range window.Hosts {
+		h := &window.Hosts[i]
+		if !h.Notified || h.Disabled {
+			continue
+		}
+		if _, err := client.SwitchHostMonitoringNotifications(h.ID, false, nil); err != nil {
+			return fmt.Errorf("error disabling notifications of host %s: %w", h.Name, err)
+		}
+		h.Disabled = true
+		if err := save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreMaintenanceNotifications switches back on the notifications switched
+// off by a window, saving the state after each host.
+func restoreMaintenanceNotifications(window *maintenanceWindow, save func() error) error {
+	for i := range window.Hosts {
+		h := &window.Hosts[i]
+		if !h.Disabled {
+			continue
+		}
+		if _, err := client.SwitchHostMonitoringNotifications(h.ID, true, nil); err != nil {
+			return fmt.Errorf("error restoring notifications of host %s: %w", h.Name, err)
+		}
+		h.Disabled = false
+		if err := save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hostNotificationsEnabled reports whether a decoded host currently sends
+// notifications. Hosts that do not expose the information are assumed to.
+func hostNotificationsEnabled(host map[string]interface{}) bool {
+	for _, key := range []string{"notificationsEnabled", "isNotified"} {
+		if enabled, ok := host[key].(bool); ok {
+			return enabled
+		}
+	}
+	return true
+}
@@ -23,6 +23,7 @@ func init() {
 	}
 	checkRTMSHealthCmd.Flags().IntSlice("integration-services", nil, "List of service identifiers used to test the delay of integration of monitoring results")
 	checkRTMSHealthCmd.Flags().Int("integration-delay", 0, "Delay allowed in seconds to test the delay of integration of monitoring results")
+	addPluginFlags(checkRTMSHealthCmd, "RTMS", "time", "2s", "5s", "response time")
 	monitoringCmd.AddCommand(checkRTMSHealthCmd)
 
 	// Check SLA Calculator health
@@ -32,6 +33,7 @@ func init() {
 		RunE:  checkSLACalculatorHealth,
 	}
 	checkSLACalculatorHealthCmd.Flags().Int("update-delay", 0, "Delay allowed in seconds between the current time and the last update of a ticket's SLA")
+	addPluginFlags(checkSLACalculatorHealthCmd, "SLA CALCULATOR", "time", "2s", "5s", "response time")
 	monitoringCmd.AddCommand(checkSLACalculatorHealthCmd)
 }
 
@@ -39,6 +41,13 @@ func checkRTMSHealth(cmd *cobra.Command, args []string) error {
 	integrationServices, _ := cmd.Flags().GetIntSlice("integration-services")
 	integrationDelay, _ := cmd.Flags().GetInt("integration-delay")
 	format, _ := cmd.Flags().GetString("format")
+	plugin, _ := cmd.Flags().GetBool("plugin")
+
+	if plugin {
+		return runHealthPlugin(cmd, "RTMS", func() ([]byte, error) {
+			return client.CheckRTMSHealth(integrationServices, integrationDelay)
+		})
+	}
 
 	response, err := client.CheckRTMSHealth(integrationServices, integrationDelay)
 	if err != nil {
@@ -55,6 +64,13 @@ func checkRTMSHealth(cmd *cobra.Command, args []string) error {
 func checkSLACalculatorHealth(cmd *cobra.Command, args []string) error {
 	updateDelay, _ := cmd.Flags().GetInt("update-delay")
 	format, _ := cmd.Flags().GetString("format")
+	plugin, _ := cmd.Flags().GetBool("plugin")
+
+	if plugin {
+		return runHealthPlugin(cmd, "SLA CALCULATOR", func() ([]byte, error) {
+			return client.CheckSLACalculatorHealth(updateDelay)
+		})
+	}
 
 	response, err := client.CheckSLACalculatorHealth(updateDelay)
 	if err != nil {
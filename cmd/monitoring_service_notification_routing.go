@@ -0,0 +1,294 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	// Perimeters subcommand
+	perimetersCmd := &cobra.Command{
+		Use:   "perimeters",
+		Short: "Manage notification perimeters",
+	}
+	monitoringServiceNotificationsCmd.AddCommand(perimetersCmd)
+
+	// List perimeters
+	listPerimetersCmd := &cobra.Command{
+		Use:   "list",
+		Short: "Get a list of notification perimeters",
+	}
+	updateListCommand(listPerimetersCmd, "/monitoringServices/notifications/perimeters", func() map[string]string {
+		return map[string]string{
+			"cloudTempleId": cloudTempleID,
+		}
+	})
+	perimetersCmd.AddCommand(listPerimetersCmd)
+
+	// Get perimeter details
+	getPerimeterCmd := &cobra.Command{
+		Use:   "details [id]",
+		Short: "Get notification perimeter details",
+		Args:  cobra.ExactArgs(1),
+		RunE:  getNotificationPerimeter,
+	}
+	perimetersCmd.AddCommand(getPerimeterCmd)
+
+	// Edit perimeter
+	editPerimeterCmd := &cobra.Command{
+		Use:   "edit [id]",
+		Short: "Edit notification perimeter",
+		Args:  cobra.ExactArgs(1),
+		RunE:  editNotificationPerimeter,
+	}
+	editPerimeterCmd.Flags().String("name", "", "New perimeter name")
+	editPerimeterCmd.Flags().IntSlice("staffs", nil, "Staff identifiers notified for this perimeter")
+	editPerimeterCmd.Flags().IntSlice("triggers", nil, "Trigger identifiers of this perimeter")
+	editPerimeterCmd.Flags().IntSlice("time-periods", nil, "Time period identifiers of this perimeter")
+	editPerimeterCmd.Flags().StringSlice("hosts", nil, "Host IDs or names covered by this perimeter")
+	editPerimeterCmd.Flags().StringSlice("host-tags", nil, "Host tag IDs or labels covered by this perimeter")
+	editPerimeterCmd.RegisterFlagCompletionFunc("hosts", completeNames("/hosts"))
+	editPerimeterCmd.RegisterFlagCompletionFunc("host-tags", completeNames("/hosts/tags"))
+	perimetersCmd.AddCommand(editPerimeterCmd)
+
+	// Staffs subcommand
+	staffsCmd := &cobra.Command{
+		Use:   "staffs",
+		Short: "Manage notification staffs",
+	}
+	monitoringServiceNotificationsCmd.AddCommand(staffsCmd)
+
+	// List staffs
+	listStaffsCmd := &cobra.Command{
+		Use:   "list",
+		Short: "Get a list of notification staffs",
+	}
+	updateListCommand(listStaffsCmd, "/monitoringServices/notifications/staffs", func() map[string]string {
+		return map[string]string{
+			"cloudTempleId": cloudTempleID,
+		}
+	})
+	staffsCmd.AddCommand(listStaffsCmd)
+
+	// Get staff details
+	getStaffCmd := &cobra.Command{
+		Use:   "details [id]",
+		Short: "Get notification staff details",
+		Args:  cobra.ExactArgs(1),
+		RunE:  getNotificationStaff,
+	}
+	staffsCmd.AddCommand(getStaffCmd)
+
+	// Time periods subcommand
+	timePeriodsCmd := &cobra.Command{
+		Use:   "time-periods",
+		Short: "Manage notification time periods",
+	}
+	monitoringServiceNotificationsCmd.AddCommand(timePeriodsCmd)
+
+	// List time periods
+	listTimePeriodsCmd := &cobra.Command{
+		Use:   "list",
+		Short: "Get a list of notification time periods",
+	}
+	updateListCommand(listTimePeriodsCmd, "/monitoringServices/notifications/timePeriods", func() map[string]string {
+		return map[string]string{
+			"cloudTempleId": cloudTempleID,
+		}
+	})
+	timePeriodsCmd.AddCommand(listTimePeriodsCmd)
+
+	// Time period stops subcommand
+	timePeriodStopsCmd := &cobra.Command{
+		Use:   "time-period-stops",
+		Short: "Manage notification time period stops",
+	}
+	monitoringServiceNotificationsCmd.AddCommand(timePeriodStopsCmd)
+
+	// List time period stops
+	listTimePeriodStopsCmd := &cobra.Command{
+		Use:   "list",
+		Short: "Get a list of notification time period stops",
+	}
+	updateListCommand(listTimePeriodStopsCmd, "/monitoringServices/notifications/timePeriodStops", func() map[string]string {
+		return map[string]string{
+			"cloudTempleId": cloudTempleID,
+		}
+	})
+	timePeriodStopsCmd.AddCommand(listTimePeriodStopsCmd)
+
+	// Get time period stop details
+	getTimePeriodStopCmd := &cobra.Command{
+		Use:   "details [id]",
+		Short: "Get notification time period stop details",
+		Args:  cobra.ExactArgs(1),
+		RunE:  getNotificationTimePeriodStop,
+	}
+	timePeriodStopsCmd.AddCommand(getTimePeriodStopCmd)
+
+	// Remove time period stop
+	removeTimePeriodStopCmd := &cobra.Command{
+		Use:   "remove [id]",
+		Short: "Remove notification time period stop",
+		Args:  cobra.ExactArgs(1),
+		RunE:  removeNotificationTimePeriodStop,
+	}
+	timePeriodStopsCmd.AddCommand(removeTimePeriodStopCmd)
+
+	// Triggers subcommand
+	triggersCmd := &cobra.Command{
+		Use:   "triggers",
+		Short: "Manage notification triggers",
+	}
+	monitoringServiceNotificationsCmd.AddCommand(triggersCmd)
+
+	// List triggers
+	listTriggersCmd := &cobra.Command{
+		Use:   "list",
+		Short: "Get a list of notification triggers",
+	}
+	updateListCommand(listTriggersCmd, "/monitoringServices/notifications/triggers", func() map[string]string {
+		return map[string]string{
+			"cloudTempleId": cloudTempleID,
+		}
+	})
+	triggersCmd.AddCommand(listTriggersCmd)
+
+	// Get trigger details
+	getTriggerCmd := &cobra.Command{
+		Use:   "details [id]",
+		Short: "Get notification trigger details",
+		Args:  cobra.ExactArgs(1),
+		RunE:  getNotificationTriggerDetails,
+	}
+	triggersCmd.AddCommand(getTriggerCmd)
+}
+
+func getNotificationPerimeter(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	response, err := client.GetNotificationPerimeter(args[0])
+	if err != nil {
+		return err
+	}
+	formattedOutput, err := formatOutput(response, format)
+	if err != nil {
+		return err
+	}
+	fmt.Println(formattedOutput)
+	return nil
+}
+
+func editNotificationPerimeter(cmd *cobra.Command, args []string) error {
+	name, _ := cmd.Flags().GetString("name")
+	format, _ := cmd.Flags().GetString("format")
+
+	perimeterData := make(map[string]interface{})
+	if name != "" {
+		perimeterData["name"] = name
+	}
+	for flag, field := range map[string]string{
+		"staffs":       "staffs",
+		"triggers":     "triggers",
+		"time-periods": "timePeriods",
+	} {
+		if cmd.Flags().Changed(flag) {
+			ids, _ := cmd.Flags().GetIntSlice(flag)
+			perimeterData[field] = ids
+		}
+	}
+	for _, r := range []struct {
+		flag, field string
+		resolve     func([]string) ([]int, error)
+	}{
+		{"hosts", "hosts", resolveHostIDs},
+		{"host-tags", "hostTags", resolveHostTagIDs},
+	} {
+		if cmd.Flags().Changed(r.flag) {
+			refs, _ := cmd.Flags().GetStringSlice(r.flag)
+			ids, err := r.resolve(refs)
+			if err != nil {
+				return err
+			}
+			perimeterData[r.field] = ids
+		}
+	}
+	if len(perimeterData) == 0 {
+		return fmt.Errorf("nothing to edit, set at least one flag")
+	}
+
+	response, err := client.UpdateNotificationPerimeter(args[0], perimeterData)
+	if err != nil {
+		return err
+	}
+	formattedOutput, err := formatOutput(response, format)
+	if err != nil {
+		return err
+	}
+	fmt.Println(formattedOutput)
+	return nil
+}
+
+func getNotificationStaff(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	response, err := client.GetNotificationStaff(args[0])
+	if err != nil {
+		return err
+	}
+	formattedOutput, err := formatOutput(response, format)
+	if err != nil {
+		return err
+	}
+	fmt.Println(formattedOutput)
+	return nil
+}
+
+func getNotificationTimePeriodStop(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	response, err := client.GetNotificationTimePeriodStop(args[0])
+	if err != nil {
+		return err
+	}
+	formattedOutput, err := formatOutput(response, format)
+	if err != nil {
+		return err
+	}
+	fmt.Println(formattedOutput)
+	return nil
+}
+
+func removeNotificationTimePeriodStop(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	confirmed, err := confirmDeletion("time-period-stop", args[0], client.GetNotificationTimePeriodStop)
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		fmt.Println("Operation cancelled.")
+		return nil
+	}
+	response, err := client.RemoveNotificationTimePeriodStop(args[0])
+	if err != nil {
+		return err
+	}
+	formattedOutput, err := formatOutput(response, format)
+	if err != nil {
+		return err
+	}
+	fmt.Println(formattedOutput)
+	return nil
+}
+
+func getNotificationTriggerDetails(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	response, err := client.GetNotificationTriggerDetails(args[0])
+	if err != nil {
+		return err
+	}
+	formattedOutput, err := formatOutput(response, format)
+	if err != nil {
+		return err
+	}
+	fmt.Println(formattedOutput)
+	return nil
+}
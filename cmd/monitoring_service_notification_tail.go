@@ -0,0 +1,330 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const notificationTailStateFile = "notifications-tail.json"
+
+// notificationCursor is the position of the tail in the notifications of a
+// tenant, persisted between runs.
+type notificationCursor struct {
+	LastID   int64  `json:"lastId"`
+	LastSeen string `json:"lastSeen"`
+	// Seen holds the most recent IDs, to skip notifications returned again by
+	// overlapping pages.
+	Seen []string `json:"seen"`
+}
+
+const notificationTailSeenSize = 500
+
+func init() {
+	// Tail notifications
+	tailNotificationsCmd := &cobra.Command{
+		Use:   "tail",
+		Short: "Follow new notifications",
+		Long: `Poll the notifications and print the new ones as they arrive.
+
+The last notification seen is saved per Cloud Temple ID in the configuration
+directory, so that a restarted tail resumes where it stopped. On the first run,
+only the latest notifications are printed (see --lines).`,
+		RunE: tailNotifications,
+	}
+	tailNotificationsCmd.Flags().String("interval", "10s", "Polling interval")
+	tailNotificationsCmd.Flags().Int("lines", 10, "Number of latest notifications printed when no position is saved")
+	tailNotificationsCmd.Flags().IntSlice("staffs", nil, "Filter by staff identifiers")
+	tailNotificationsCmd.Flags().IntSlice("perimeters", nil, "Filter by perimeter identifiers")
+	tailNotificationsCmd.Flags().StringSlice("state", nil, "Filter by state (OK, WARNING, CRITICAL, UNKNOWN)")
+	tailNotificationsCmd.Flags().StringSlice("hosts", nil, "Filter by host names or IDs")
+	tailNotificationsCmd.Flags().Bool("once", false, "Print the notifications received since the last run and exit")
+	tailNotificationsCmd.Flags().Bool("reset", false, "Ignore the saved position")
+	tailNotificationsCmd.RegisterFlagCompletionFunc("hosts", completeNames("/hosts"))
+	monitoringServiceNotificationsCmd.AddCommand(tailNotificationsCmd)
+}
+
+func tailNotifications(cmd *cobra.Command, args []string) error {
+	intervalFlag, _ := cmd.Flags().GetString("interval")
+	lines, _ := cmd.Flags().GetInt("lines")
+	staffs, _ := cmd.Flags().GetIntSlice("staffs")
+	perimeters, _ := cmd.Flags().GetIntSlice("perimeters")
+	states, _ := cmd.Flags().GetStringSlice("state")
+	hosts, _ := cmd.Flags().GetStringSlice("hosts")
+	once, _ := cmd.Flags().GetBool("once")
+	reset, _ := cmd.Flags().GetBool("reset")
+	format, _ := cmd.Flags().GetString("format")
+
+	interval, err := parseDuration(intervalFlag)
+	if err != nil {
+		return err
+	}
+	if interval < time.Second {
+		return fmt.Errorf("interval must be at least 1s")
+	}
+
+	params := map[string]string{
+		"cloudTempleId": cloudTempleID,
+		"order":         "DESC",
+		"orderBy":       "id",
+	}
+	if len(staffs) > 0 {
+		params["staffs[]"] = intSliceToString(staffs)
+	}
+	if len(perimeters) > 0 {
+		params["perimeters[]"] = intSliceToString(perimeters)
+	}
+
+	cursors := make(map[string]*notificationCursor)
+	if err := loadState(notificationTailStateFile, &cursors); err != nil {
+		return err
+	}
+	cursor, ok := cursors[cloudTempleID]
+	if !ok || reset {
+		cursor = &notificationCursor{}
+		cursors[cloudTempleID] = cursor
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		notifications, err := fetchNewNotifications(params, cursor, lines)
+		if err != nil {
+			if once {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Error fetching notifications: %v\n", err)
+		}
+
+		for _, n := range notifications {
+			advanceNotificationCursor(cursor, n)
+			if !notificationMatches(n, states, hosts) {
+				continue
+			}
+			if err := printNotification(n, format); err != nil {
+				return err
+			}
+		}
+		if len(notifications) > 0 {
+			if err := saveState(notificationTailStateFile, cursors); err != nil {
+				return err
+			}
+		}
+
+		if once {
+			return nil
+		}
+		select {
+		case <-interrupt:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// fetchNewNotifications returns the notifications newer than the cursor,
+// oldest first. Pages are read from the most recent notification until the
+// cursor is reached. Without a saved position, only the latest notifications
+// are returned.
+func fetchNewNotifications(params map[string]string, cursor *notificationCursor, latest int) ([]map[string]interface{}, error) {
+	seen := make(map[string]bool)
+	for _, id := range cursor.Seen {
+		seen[id] = true
+	}
+	firstRun := cursor.LastID == 0 && len(cursor.Seen) == 0
+
+	pageSize := batchSize
+	if firstRun {
+		pageSize = latest
+	}
+	if pageSize <= 0 {
+		pageSize = 100
+	}
+
+	var notifications []map[string]interface{}
+	for page := 1; ; page++ {
+		pageParams := make(map[string]string)
+		for k, v := range params {
+			pageParams[k] = v
+		}
+		pageParams["page"] = strconv.Itoa(page)
+		pageParams["itemsPerPage"] = strconv.Itoa(pageSize)
+
+		response, err := client.GetAllNotifications(cloudTempleID, pageParams)
+		if err != nil {
+			return nil, err
+		}
+		data, err := decodeData(response)
+		if err != nil {
+			return nil, err
+		}
+		items, _ := data.([]interface{})
+
+		reachedCursor := false
+		for _, item := range items {
+			n, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			id := resourceID(n)
+			if numericID, err := strconv.ParseInt(id, 10, 64); err == nil && numericID <= cursor.LastID {
+				reachedCursor = true
+				continue
+			}
+			if seen[id] {
+				reachedCursor = true
+				continue
+			}
+			seen[id] = true
+			notifications = append(notifications, n)
+		}
+
+		if firstRun || reachedCursor || len(items) < pageSize {
+			break
+		}
+	}
+
+	// Pages are requested newest first
+	for i, j := 0, len(notifications)-1; i < j; i, j = i+1, j-1 {
+		notifications[i], notifications[j] = notifications[j], notifications[i]
+	}
+	ids := make([]int64, len(notifications))
+	for i, n := range notifications {
+		id, err := strconv.ParseInt(resourceID(n), 10, 64)
+		if err != nil {
+			return notifications, nil
+		}
+		ids[i] = id
+	}
+	sort.Sort(notificationsByID{notifications, ids})
+	return notifications, nil
+}
+
+type notificationsByID struct {
+	notifications []map[string]interface{}
+	ids           []int64
+}
+
+func (s notificationsByID) Len() int           { return len(s.ids) }
+func (s notificationsByID) Less(i, j int) bool { return s.ids[i] < s.ids[j] }
+func (s notificationsByID) Swap(i, j int) {
+	s.notifications[i], s.notifications[j] = s.notifications[j], s.notifications[i]
+	s.ids[i], s.ids[j] = s.ids[j], s.ids[i]
+}
+
+func advanceNotificationCursor(cursor *notificationCursor, n map[string]interface{}) {
+	id := resourceID(n)
+	if numericID, err := strconv.ParseInt(id, 10, 64); err == nil && numericID > cursor.LastID {
+		cursor.LastID = numericID
+	}
+	if date := notificationDate(n); date != "" {
+		cursor.LastSeen = date
+	}
+	cursor.Seen = append(cursor.Seen, id)
+	if len(cursor.Seen) > notificationTailSeenSize {
+		cursor.Seen = cursor.Seen[len(cursor.Seen)-notificationTailSeenSize:]
+	}
+}
+
+// notificationMatches applies the filters that the API does not support.
+func notificationMatches(n map[string]interface{}, states, hosts []string) bool {
+	if len(states) > 0 {
+		state := fmt.Sprintf("%v", rowState(n))
+		matched := false
+		for _, s := range states {
+			if strings.EqualFold(s, state) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(hosts) > 0 {
+		hostID, hostName := notificationHost(n)
+		matched := false
+		for _, h := range hosts {
+			if h == hostID || strings.EqualFold(h, hostName) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// notificationHost returns the ID and name of the host a notification was
+// raised for, looking at the host itself or at its monitoring service.
+func notificationHost(n map[string]interface{}) (string, string) {
+	if host, ok := n["host"].(map[string]interface{}); ok {
+		return resourceID(host), resourceName(host)
+	}
+	if id, ok := n["hostId"]; ok {
+		return resourceID(map[string]interface{}{"id": id}), ""
+	}
+	for _, key := range []string{"monitoringService", "service"} {
+		if service, ok := n[key].(map[string]interface{}); ok {
+			return notificationHost(service)
+		}
+	}
+	return "", ""
+}
+
+func notificationDate(n map[string]interface{}) string {
+	for _, key := range []string{"createdAt", "date", "timestamp"} {
+		if value, ok := n[key]; ok && value != nil {
+			return fmt.Sprintf("%v", value)
+		}
+	}
+	return ""
+}
+
+// printNotification writes one notification per line: as compact JSON for the
+// json format, as a summary otherwise.
+func printNotification(n map[string]interface{}, format string) error {
+	if format == "json" {
+		line, err := json.Marshal(n)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(line))
+		return nil
+	}
+
+	_, hostName := notificationHost(n)
+	subject := n["subject"]
+	if subject == nil {
+		subject = n["content"]
+	}
+	line := fmt.Sprintf("#%s [%v]", resourceID(n), rowState(n))
+	if date := notificationDate(n); date != "" {
+		line = date + " " + line
+	}
+	if hostName != "" {
+		line += " " + hostName + ":"
+	}
+	if subject != nil {
+		line += fmt.Sprintf(" %v", subject)
+	}
+	if isTerminal(os.Stdout) {
+		line = highlight(line, "changed", rowState(n))
+	}
+	fmt.Println(line)
+	return nil
+}
@@ -0,0 +1,367 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// Policies of triage --auto.
+const (
+	triageAttach         = "attach"
+	triageAttachOrCreate = "attach-or-create"
+	triageCreate         = "create"
+)
+
+var triagePolicies = []string{triageAttach, triageAttachOrCreate, triageCreate}
+
+// triageMaxSuggestions is the number of suggestions offered per notification.
+const triageMaxSuggestions = 9
+
+func init() {
+	// Triage notifications
+	triageNotificationsCmd := &cobra.Command{
+		Use:   "triage",
+		Short: "Attach the notifications that have no ticket, one at a time",
+		Long: `Go through the notifications that are not attached to a ticket, oldest
+first. Only the notifications of the last --since are triaged, 1 day by
+default. Each notification is shown with the tickets suggested for it, and you
+choose what to do:
+
+  1-9     attach it to the suggested ticket of that number
+  #<id>   attach it to another ticket
+  c       create a ticket from the notification and attach it
+  e       the same, editing the ticket in your editor first
+  s       skip it (default)
+  q       stop
+
+Created tickets are named after the subject of the notification and described
+with its content. With --template, the ticket template is used instead, with
+the variables of the notification: id, host, hostId, service, serviceId, state,
+subject, content and date. --owner and --catalog-items take precedence over
+the template.
+
+With --auto, the notifications are triaged without prompting, following a
+policy, and a report is printed:
+
+  attach            attach to the first suggestion, skip when there is none
+  attach-or-create  attach to the first suggestion, or create a ticket
+  create            always create a ticket`,
+		Args: cobra.NoArgs,
+		RunE: triageNotifications,
+	}
+	triageNotificationsCmd.Flags().String("auto", "", "Triage without prompting, with the policy attach, attach-or-create or create (--auto alone: attach)")
+	triageNotificationsCmd.Flags().Lookup("auto").NoOptDefVal = triageAttach
+	triageNotificationsCmd.Flags().String("since", "1d", "Only triage the notifications sent since then, as a duration (e.g. 12h, 7d)")
+	triageNotificationsCmd.Flags().StringSlice("state", nil, "Filter by state (OK, WARNING, CRITICAL, UNKNOWN)")
+	triageNotificationsCmd.Flags().StringSlice("hosts", nil, "Filter by host names or IDs")
+	triageNotificationsCmd.Flags().String("owner", "", "ID, name or email address of the owner of the created tickets")
+	triageNotificationsCmd.Flags().StringSlice("catalog-items", nil, "Classification catalog items of the created tickets, as IDs, names or \"Catalog > Item\" paths")
+	triageNotificationsCmd.Flags().String("template", "", "Ticket template of the created tickets")
+	triageNotificationsCmd.Flags().Bool("dry-run", false, "With --auto, show the decisions without applying them")
+	triageNotificationsCmd.RegisterFlagCompletionFunc("hosts", completeNames("/hosts"))
+	monitoringServiceNotificationsCmd.AddCommand(triageNotificationsCmd)
+}
+
+func triageNotifications(cmd *cobra.Command, args []string) error {
+	policy, _ := cmd.Flags().GetString("auto")
+	sinceFlag, _ := cmd.Flags().GetString("since")
+	states, _ := cmd.Flags().GetStringSlice("state")
+	hosts, _ := cmd.Flags().GetStringSlice("hosts")
+	owner, _ := cmd.Flags().GetString("owner")
+	catalogItems, _ := cmd.Flags().GetStringSlice("catalog-items")
+	templateName, _ := cmd.Flags().GetString("template")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	format, _ := cmd.Flags().GetString("format")
+
+	since, err := parseDuration(sinceFlag)
+	if err != nil {
+		return fmt.Errorf("invalid --since: %w", err)
+	}
+	if since <= 0 {
+		return fmt.Errorf("--since must be a positive duration")
+	}
+	start := time.Now().Add(-since)
+
+	auto := cmd.Flags().Changed("auto")
+	if auto {
+		valid := false
+		for _, p := range triagePolicies {
+			valid = valid || p == policy
+		}
+		if !valid {
+			return fmt.Errorf("invalid --auto policy: %s. Supported policies are %s", policy, strings.Join(triagePolicies, ", "))
+		}
+	} else {
+		if dryRun {
+			return fmt.Errorf("--dry-run requires --auto")
+		}
+		if !isTerminal(os.Stdin) {
+			return fmt.Errorf("triage prompts for each notification: use --auto when not running in a terminal")
+		}
+	}
+
+	items, err := fetchRecentNotifications(map[string]string{
+		"cloudTempleId": cloudTempleID,
+		"attach":        "false",
+		"order":         "DESC",
+		"orderBy":       "id",
+	}, start)
+	if err != nil {
+		return fmt.Errorf("error fetching notifications: %w", err)
+	}
+	var notifications []map[string]interface{}
+	for _, n := range items {
+		if date := ticketDate(n, "createdAt", "date", "timestamp"); !date.IsZero() && date.Before(start) {
+			continue
+		}
+		if notificationTicketID(n) == "" && notificationMatches(n, states, hosts) {
+			notifications = append(notifications, n)
+		}
+	}
+	if len(notifications) == 0 {
+		fmt.Println("No notification to triage.")
+		return nil
+	}
+
+	draft := func(n map[string]interface{}) (*ticketTemplate, error) {
+		return notificationTicket(notificationVariables(n), templateName, owner, catalogItems)
+	}
+	if auto {
+		return triageAuto(notifications, policy, draft, dryRun, format)
+	}
+	return triageInteractive(notifications, draft)
+}
+
+// notificationTicket prepares the ticket created for a notification, from a
+// ticket template rendered with the variables of the notification, or else
+// from its subject and content.
+func notificationTicket(vars map[string]string, templateName, owner string, catalogItems []string) (*ticketTemplate, error) {
+	var t *ticketTemplate
+	if templateName != "" {
+		values := make([]string, 0, len(vars))
+		for k, v := range vars {
+			values = append(values, k+"="+v)
+		}
+		var err error
+		if t, err = loadTicketTemplate(templateName, values); err != nil {
+			return nil, err
+		}
+	} else {
+		t = &ticketTemplate{Name: vars["subject"]}
+		if t.Name == "" {
+			t.Name = strings.TrimSpace(fmt.Sprintf("%s %s on %s", vars["service"], vars["state"], vars["host"]))
+		}
+		var description strings.Builder
+		if vars["content"] != "" {
+			description.WriteString(vars["content"] + "\n\n")
+		}
+		fmt.Fprintf(&description, "Notification #%s", vars["id"])
+		if vars["service"] != "" {
+			fmt.Fprintf(&description, " of %s", vars["service"])
+		}
+		if vars["host"] != "" {
+			fmt.Fprintf(&description, " on %s", vars["host"])
+		}
+		if vars["state"] != "" {
+			fmt.Fprintf(&description, ": %s", vars["state"])
+		}
+		if vars["date"] != "" {
+			fmt.Fprintf(&description, " since %s", vars["date"])
+		}
+		t.Description = description.String()
+	}
+	if owner != "" {
+		t.Owner = owner
+	}
+	if len(catalogItems) > 0 {
+		t.CatalogItems = catalogItems
+	}
+	return t, nil
+}
+
+func triageAuto(notifications []map[string]interface{}, policy string, draft func(map[string]interface{}) (*ticketTemplate, error), dryRun bool, format string) error {
+	report := make([]interface{}, 0, len(notifications))
+	for _, n := range notifications {
+		action, ticketID, err := triageAutoNotification(resourceID(n), policy, func() (*ticketTemplate, error) {
+			return draft(n)
+		}, dryRun)
+		_, hostName := notificationHost(n)
+		_, service := notificationService(n)
+		row := map[string]interface{}{
+			"notification": resourceID(n),
+			"host":         hostName,
+			"service":      resourceName(service),
+			"state":        notificationState(n),
+			"action":       action,
+			"ticket":       ticketID,
+			"error":        "",
+		}
+		if err != nil {
+			row["action"] = "failed"
+			row["error"] = err.Error()
+		}
+		report = append(report, row)
+	}
+
+	formattedOutput, err := formatOutput(report, format)
+	if err != nil {
+		return err
+	}
+	fmt.Println(formattedOutput)
+	return nil
+}
+
+// triageAutoNotification applies a policy to a notification, and returns the
+// action taken and the ticket.
+func triageAutoNotification(id, policy string, draft func() (*ticketTemplate, error), dryRun bool) (string, string, error) {
+	if policy != triageCreate {
+		suggestions, err := ticketSuggestions(id)
+		if err != nil {
+			return "", "", fmt.Errorf("error fetching ticket suggestions: %w", err)
+		}
+		if len(suggestions) > 0 {
+			ticketID := resourceID(suggestions[0])
+			if dryRun {
+				return "attach", ticketID, nil
+			}
+			return "attached", ticketID, attachNotification(id, ticketID)
+		}
+		if policy == triageAttach {
+			return "skipped", "", nil
+		}
+	}
+
+	t, err := draft()
+	if err != nil {
+		return "", "", err
+	}
+	if dryRun {
+		return "create", "", nil
+	}
+	ticketID, _, err := createTicketFromTemplate(t)
+	if err != nil {
+		return "", ticketID, err
+	}
+	return "created", ticketID, attachNotification(id, ticketID)
+}
+
+func triageInteractive(notifications []map[string]interface{}, draft func(map[string]interface{}) (*ticketTemplate, error)) error {
+	var attached, created, skipped, failed int
+	defer func() {
+		fmt.Printf("\n%d attached (%d to new tickets), %d skipped, %d failed\n", attached, created, skipped, failed)
+	}()
+
+	for i, n := range notifications {
+		id := resourceID(n)
+		fmt.Printf("\n[%d/%d] %s\n", i+1, len(notifications), notificationLine(n))
+		if content, ok := n["content"].(string); ok && strings.TrimSpace(content) != "" && content != n["subject"] {
+			for _, line := range strings.Split(strings.TrimSpace(content), "\n") {
+				fmt.Println("    " + line)
+			}
+		}
+
+		suggestions, err := ticketSuggestions(id)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error fetching ticket suggestions: %v\n", err)
+		}
+		if len(suggestions) > triageMaxSuggestions {
+			suggestions = suggestions[:triageMaxSuggestions]
+		}
+		if len(suggestions) == 0 {
+			fmt.Println("  No suggested ticket")
+		}
+		for j, s := range suggestions {
+			fmt.Printf("  %d) %s\n", j+1, ticketLine(s))
+		}
+		choices := "#<id> other ticket, c create, e edit and create, s skip, q quit"
+		if len(suggestions) > 0 {
+			choices = fmt.Sprintf("1-%d attach, %s", len(suggestions), choices)
+		}
+
+	prompt:
+		for {
+			answer, err := promptLine(fmt.Sprintf("Action (%s) [s]: ", choices))
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			var ticketID string
+			switch answer = strings.ToLower(answer); {
+			case answer == "" || answer == "s":
+				skipped++
+				break prompt
+			case answer == "q":
+				return nil
+			case answer == "c" || answer == "e":
+				t, err := draft(n)
+				if err == nil && answer == "e" {
+					err = editNotificationTicket(t)
+				}
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					continue
+				}
+				if ticketID, _, err = createTicketFromTemplate(t); err != nil {
+					fmt.Fprintf(os.Stderr, "Error creating ticket: %v\n", err)
+					if ticketID == "" {
+						continue
+					}
+				} else {
+					fmt.Printf("Ticket #%s created\n", ticketID)
+				}
+				created++
+			case strings.HasPrefix(answer, "#"):
+				ticketID = strings.TrimPrefix(answer, "#")
+			default:
+				k, err := strconv.Atoi(answer)
+				if err != nil || k < 1 || k > len(suggestions) {
+					fmt.Fprintf(os.Stderr, "Invalid action: %s\n", answer)
+					continue
+				}
+				ticketID = resourceID(suggestions[k-1])
+			}
+
+			if err := attachNotification(id, ticketID); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				failed++
+			} else {
+				fmt.Printf("Notification #%s attached to ticket #%s\n", id, ticketID)
+				attached++
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// editNotificationTicket lets the user edit the ticket prepared for a
+// notification before it is created.
+func editNotificationTicket(t *ticketTemplate) error {
+	frontMatter := yaml.MapSlice{
+		{Key: "name", Value: t.Name},
+		{Key: "owner", Value: t.Owner},
+		{Key: "catalog-items", Value: t.CatalogItems},
+	}
+	values, body, err := composeInEditor("Ticket for the notification: the description goes below the front matter.\nLeave the description empty to abort.", frontMatter, t.Description)
+	if err != nil {
+		return err
+	}
+	if body == "" {
+		return fmt.Errorf("aborted: empty description")
+	}
+	t.Description = body
+	t.Name = frontMatterString(values, "name")
+	t.Owner = frontMatterString(values, "owner")
+	t.CatalogItems, err = frontMatterStrings(values, "catalog-items")
+	return err
+}
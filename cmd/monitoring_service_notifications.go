@@ -2,6 +2,9 @@ package cmd
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -195,4 +198,85 @@ func detachNotificationFromTicket(cmd *cobra.Command, args []string) error {
 	}
 	fmt.Println(formattedOutput)
 	return nil
-}
\ No newline at end of file
+}
+
+// ticketSuggestions returns the tickets suggested for a notification, best
+// first.
+func ticketSuggestions(id string) ([]map[string]interface{}, error) {
+	response, err := client.GetTicketSuggestions(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := decodeData(response)
+	if err != nil {
+		return nil, err
+	}
+	var suggestions []map[string]interface{}
+	switch v := data.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if ticket, ok := item.(map[string]interface{}); ok && resourceID(ticket) != "" {
+				suggestions = append(suggestions, ticket)
+			}
+		}
+	case map[string]interface{}:
+		if resourceID(v) != "" {
+			suggestions = append(suggestions, v)
+		}
+	}
+	return suggestions, nil
+}
+
+// notificationTicketID returns the ID of the ticket a notification is
+// attached to, or "" when it is not attached.
+func notificationTicketID(n map[string]interface{}) string {
+	for _, key := range []string{"ticket", "ticketId"} {
+		switch ticket := n[key].(type) {
+		case map[string]interface{}:
+			return resourceID(ticket)
+		case float64:
+			return strconv.FormatFloat(ticket, 'f', -1, 64)
+		case string:
+			return ticket
+		}
+	}
+	return ""
+}
+
+// notificationVariables describes a notification for ticket templates.
+func notificationVariables(n map[string]interface{}) map[string]string {
+	hostID, hostName := notificationHost(n)
+	serviceID, service := notificationService(n)
+	vars := map[string]string{
+		"id":        resourceID(n),
+		"host":      hostName,
+		"hostId":    hostID,
+		"service":   resourceName(service),
+		"serviceId": serviceID,
+		"state":     notificationState(n),
+		"subject":   "",
+		"content":   "",
+		"date":      "",
+	}
+	for _, key := range []string{"subject", "content"} {
+		if value, ok := n[key].(string); ok {
+			vars[key] = strings.TrimSpace(value)
+		}
+	}
+	if date := ticketDate(n, "createdAt", "date", "timestamp"); !date.IsZero() {
+		vars["date"] = date.Format(time.RFC3339)
+	}
+	return vars
+}
+
+// attachNotification attaches a notification to a ticket given by its ID.
+func attachNotification(id, ticketID string) error {
+	ticket, err := strconv.Atoi(ticketID)
+	if err != nil {
+		return fmt.Errorf("invalid ticket ID: %s", ticketID)
+	}
+	if _, err := client.AttachNotificationToTicket(id, ticket); err != nil {
+		return fmt.Errorf("error attaching notification %s to ticket %s: %w", id, ticketID, err)
+	}
+	return nil
+}
@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -21,31 +23,13 @@ func init() {
 		Use:   "metric-history [service-id]",
 		Short: "Get a list of metrics versions for a given monitoring service",
 		Args:  cobra.ExactArgs(1),
+		RunE:  getMetricHistory,
 	}
-	getMetricHistoryCmd.Flags().String("start-date", "", "Start date timestamp or milliseconds of searched period")
-	getMetricHistoryCmd.Flags().String("end-date", "", "End date timestamp or milliseconds of searched period")
+	getMetricHistoryCmd.Flags().String("start-date", "", "Start date of searched period: RFC3339, YYYY-MM-DD, timestamp in seconds or milliseconds, or relative (e.g. -24h)")
+	getMetricHistoryCmd.Flags().String("end-date", "", "End date of searched period, in the same formats as --start-date")
 	getMetricHistoryCmd.Flags().StringSlice("metric-name", nil, "List of metric names")
 	getMetricHistoryCmd.Flags().String("version-order", "", "Version order: asc or desc")
-	updateListCommand(getMetricHistoryCmd, "/monitoringServices/{id}/metricHistory", func() map[string]string {
-		params := make(map[string]string)
-		startDate, _ := getMetricHistoryCmd.Flags().GetString("start-date")
-		if startDate != "" {
-			params["startDate"] = startDate
-		}
-		endDate, _ := getMetricHistoryCmd.Flags().GetString("end-date")
-		if endDate != "" {
-			params["endDate"] = endDate
-		}
-		metricNames, _ := getMetricHistoryCmd.Flags().GetStringSlice("metric-name")
-		if len(metricNames) > 0 {
-			params["metricName[]"] = strings.Join(metricNames, ",")
-		}
-		versionOrder, _ := getMetricHistoryCmd.Flags().GetString("version-order")
-		if versionOrder != "" {
-			params["versionOrder"] = versionOrder
-		}
-		return params
-	})
+	getMetricHistoryCmd.Flags().String("export", "", "Export the metric values as csv, influx (line protocol) or openmetrics")
 	monitoringServicePerformanceCmd.AddCommand(getMetricHistoryCmd)
 
 	// Get graph configurations
@@ -53,16 +37,9 @@ func init() {
 		Use:   "graph-configurations [service-id]",
 		Short: "Get a list of graph configurations for a given monitoring service",
 		Args:  cobra.ExactArgs(1),
+		RunE:  getGraphConfigurations,
 	}
 	getGraphConfigurationsCmd.Flags().String("label", "", "Filter graph by a string contained in label field")
-	updateListCommand(getGraphConfigurationsCmd, "/monitoringServices/{id}/graphs", func() map[string]string {
-		params := make(map[string]string)
-		label, _ := getGraphConfigurationsCmd.Flags().GetString("label")
-		if label != "" {
-			params["label"] = label
-		}
-		return params
-	})
 	monitoringServicePerformanceCmd.AddCommand(getGraphConfigurationsCmd)
 }
 
@@ -74,13 +51,19 @@ func getMetricHistory(cmd *cobra.Command, args []string) error {
 	endDate, _ := cmd.Flags().GetString("end-date")
 	metricNames, _ := cmd.Flags().GetStringSlice("metric-name")
 	versionOrder, _ := cmd.Flags().GetString("version-order")
+	export, _ := cmd.Flags().GetString("export")
 
 	params := make(map[string]string)
-	if startDate != "" {
-		params["startDate"] = startDate
-	}
-	if endDate != "" {
-		params["endDate"] = endDate
+	now := time.Now()
+	for param, value := range map[string]string{"startDate": startDate, "endDate": endDate} {
+		if value == "" {
+			continue
+		}
+		date, err := parseTime(value, now)
+		if err != nil {
+			return err
+		}
+		params[param] = strconv.FormatInt(date.Unix(), 10)
 	}
 	if len(metricNames) > 0 {
 		params["metricName[]"] = strings.Join(metricNames, ",")
@@ -89,6 +72,10 @@ func getMetricHistory(cmd *cobra.Command, args []string) error {
 		params["versionOrder"] = versionOrder
 	}
 
+	if export != "" {
+		return exportMetricHistory(serviceID, params, export)
+	}
+
 	dataChan, errChan := client.StreamData(fmt.Sprintf("/monitoringServices/%s/metricHistory", serviceID), params, batchSize)
 
 	for item := range dataChan {
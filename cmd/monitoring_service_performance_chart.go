@@ -0,0 +1,432 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// metricThresholds are the warning and critical levels drawn on charts.
+type metricThresholds struct {
+	Unit     string
+	Warning  float64
+	Critical float64
+	HasWarn  bool
+	HasCrit  bool
+}
+
+func init() {
+	// Chart metric history
+	chartMetricCmd := &cobra.Command{
+		Use:   "chart [service-id]",
+		Short: "Draw the metric history of a monitoring service in the terminal",
+		Long: `Draw the history of a metric as a line chart made of Unicode braille
+characters, or as a sparkline of block characters. Warning and critical
+thresholds come from the graph configurations of the service, or else from the
+performance data.`,
+		Args: cobra.ExactArgs(1),
+		RunE: chartMetricHistory,
+	}
+	chartMetricCmd.Flags().String("metric", "", "Name of the metric to draw")
+	chartMetricCmd.Flags().String("since", "6h", "Duration of the period to draw, up to now")
+	chartMetricCmd.Flags().String("style", "braille", "Chart style: braille or blocks")
+	chartMetricCmd.Flags().Int("width", 72, "Width of the chart in characters")
+	chartMetricCmd.Flags().Int("height", 12, "Height of the braille chart in lines")
+	chartMetricCmd.MarkFlagRequired("metric")
+	monitoringServicePerformanceCmd.AddCommand(chartMetricCmd)
+}
+
+func chartMetricHistory(cmd *cobra.Command, args []string) error {
+	serviceID := args[0]
+	metric, _ := cmd.Flags().GetString("metric")
+	sinceFlag, _ := cmd.Flags().GetString("since")
+	style, _ := cmd.Flags().GetString("style")
+	width, _ := cmd.Flags().GetInt("width")
+	height, _ := cmd.Flags().GetInt("height")
+
+	if style != "braille" && style != "blocks" {
+		return fmt.Errorf("unsupported chart style: %s. Supported styles are braille and blocks", style)
+	}
+	if width < 10 || height < 2 {
+		return fmt.Errorf("the chart must be at least 10 characters wide and 2 lines high")
+	}
+	since, err := parseDuration(sinceFlag)
+	if err != nil {
+		return err
+	}
+
+	end := time.Now()
+	start := end.Add(-since)
+	points, err := fetchMetricPoints(serviceID, metric, start, end)
+	if err != nil {
+		return err
+	}
+	if len(points) == 0 {
+		fmt.Println("No data found.")
+		return nil
+	}
+	thresholds, err := fetchMetricThresholds(serviceID, metric, points)
+	if err != nil {
+		return err
+	}
+
+	color := isTerminal(os.Stdout)
+	title := metric
+	if thresholds.Unit != "" {
+		title += " (" + thresholds.Unit + ")"
+	}
+	fmt.Printf("%s - service %s - last %s\n", title, serviceID, sinceFlag)
+	if style == "blocks" {
+		fmt.Print(renderSparkline(points, thresholds, width, color))
+	} else {
+		fmt.Print(renderBrailleChart(points, thresholds, start, end, width, height, color))
+	}
+	fmt.Println(summarizeMetric(points, thresholds.Unit))
+	return nil
+}
+
+// fetchMetricPoints returns the points of one metric over a period, oldest
+// first.
+func fetchMetricPoints(serviceID, metric string, start, end time.Time) ([]metricPoint, error) {
+	params := map[string]string{
+		"startDate":    strconv.FormatInt(start.Unix(), 10),
+		"endDate":      strconv.FormatInt(end.Unix(), 10),
+		"metricName[]": metric,
+		"versionOrder": "asc",
+	}
+	items, err := fetchAll(fmt.Sprintf("/monitoringServices/%s/metricHistory", serviceID), params)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching metric history: %w", err)
+	}
+
+	var points []metricPoint
+	for _, item := range items {
+		for _, point := range metricPoints(item) {
+			if point.Metric == metric && !point.Time.Before(start) && !point.Time.After(end) {
+				points = append(points, point)
+			}
+		}
+	}
+	// Keep the points sorted even if the API ignores the version order
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].Time.Before(points[j].Time)
+	})
+	return points, nil
+}
+
+// fetchMetricThresholds looks for the thresholds of a metric in the graph
+// configurations of the service, falling back to the thresholds of the most
+// recent point.
+func fetchMetricThresholds(serviceID, metric string, points []metricPoint) (metricThresholds, error) {
+	var thresholds metricThresholds
+
+	response, err := client.GetGraphConfigurations(serviceID, nil)
+	if err != nil {
+		return thresholds, fmt.Errorf("error fetching graph configurations: %w", err)
+	}
+	graphs, err := decodeData(response)
+	if err != nil {
+		return thresholds, err
+	}
+	if list, ok := graphs.([]interface{}); ok {
+		for _, graph := range list {
+			if g, ok := graph.(map[string]interface{}); ok && graphThresholds(g, metric, &thresholds) {
+				break
+			}
+		}
+	}
+
+	if len(points) > 0 {
+		last := points[len(points)-1]
+		if thresholds.Unit == "" {
+			thresholds.Unit = last.Unit
+		}
+		if !thresholds.HasWarn {
+			thresholds.Warning, thresholds.HasWarn = thresholdValue(last.Warn)
+		}
+		if !thresholds.HasCrit {
+			thresholds.Critical, thresholds.HasCrit = thresholdValue(last.Crit)
+		}
+	}
+	return thresholds, nil
+}
+
+// graphThresholds fills thresholds from a graph configuration when it draws
+// the metric. Thresholds can be set on the metric entry or on the graph.
+func graphThresholds(graph map[string]interface{}, metric string, thresholds *metricThresholds) bool {
+	found := false
+	var entry map[string]interface{}
+	switch metrics := graph["metrics"].(type) {
+	case []interface{}:
+		for _, m := range metrics {
+			switch value := m.(type) {
+			case string:
+				found = found || value == metric
+			case map[string]interface{}:
+				if stringField(value, "name", "metric", "label") == metric {
+					found, entry = true, value
+				}
+			}
+		}
+	case map[string]interface{}:
+		if value, ok := metrics[metric]; ok {
+			found = true
+			entry, _ = value.(map[string]interface{})
+		}
+	}
+	if !found {
+		return false
+	}
+
+	for _, source := range []map[string]interface{}{graph, entry} {
+		if source == nil {
+			continue
+		}
+		if unit := stringField(source, "unit", "uom"); unit != "" {
+			thresholds.Unit = unit
+		}
+		if warning, ok := numberField(source, "warning", "warn"); ok {
+			thresholds.Warning, thresholds.HasWarn = warning, true
+		}
+		if critical, ok := numberField(source, "critical", "crit"); ok {
+			thresholds.Critical, thresholds.HasCrit = critical, true
+		}
+	}
+	return true
+}
+
+// summarizeMetric returns the minimum, maximum, average and last values.
+func summarizeMetric(points []metricPoint, unit string) string {
+	if len(points) == 0 {
+		return "No data"
+	}
+	minimum, maximum, sum := math.Inf(1), math.Inf(-1), 0.0
+	for _, p := range points {
+		minimum = math.Min(minimum, p.Value)
+		maximum = math.Max(maximum, p.Value)
+		sum += p.Value
+	}
+	format := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64) + unit
+	}
+	avg := math.Round(sum/float64(len(points))*1000) / 1000
+	return fmt.Sprintf("min %s, max %s, avg %s, last %s (%d points)", format(minimum), format(maximum), format(avg), format(points[len(points)-1].Value), len(points))
+}
+
+// chartRange returns the bounds of the y axis, including the thresholds so
+// that they are always visible.
+func chartRange(points []metricPoint, thresholds metricThresholds) (float64, float64) {
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, p := range points {
+		low = math.Min(low, p.Value)
+		high = math.Max(high, p.Value)
+	}
+	if thresholds.HasWarn {
+		low, high = math.Min(low, thresholds.Warning), math.Max(high, thresholds.Warning)
+	}
+	if thresholds.HasCrit {
+		low, high = math.Min(low, thresholds.Critical), math.Max(high, thresholds.Critical)
+	}
+	if low > 0 && low < high/2 {
+		low = 0
+	}
+	if high == low {
+		high = low + 1
+	}
+	return low, high
+}
+
+// Offsets of the braille dots in a cell of 2 columns by 4 rows
+var brailleDots = [4][2]rune{{0x01, 0x08}, {0x02, 0x10}, {0x04, 0x20}, {0x40, 0x80}}
+
+// renderBrailleChart draws the points as a line, with dotted threshold lines.
+func renderBrailleChart(points []metricPoint, thresholds metricThresholds, start, end time.Time, width, height int, color bool) string {
+	low, high := chartRange(points, thresholds)
+	dotsX, dotsY := width*2, height*4
+
+	data := make([][]rune, height)
+	levels := make([][]rune, height)
+	levelColors := make([][]string, height)
+	for i := range data {
+		data[i] = make([]rune, width)
+		levels[i] = make([]rune, width)
+		levelColors[i] = make([]string, width)
+	}
+	setDot := func(grid [][]rune, x, y int) {
+		if x < 0 || x >= dotsX || y < 0 || y >= dotsY {
+			return
+		}
+		row := dotsY - 1 - y
+		grid[row/4][x/2] |= brailleDots[row%4][x%2]
+	}
+	toY := func(v float64) int {
+		return int(math.Round((v - low) / (high - low) * float64(dotsY-1)))
+	}
+
+	// Average the points falling in each column of dots
+	sums := make([]float64, dotsX)
+	counts := make([]int, dotsX)
+	span := end.Sub(start)
+	for _, p := range points {
+		x := int(float64(p.Time.Sub(start)) / float64(span) * float64(dotsX-1))
+		if x >= 0 && x < dotsX {
+			sums[x] += p.Value
+			counts[x]++
+		}
+	}
+	previous := -1
+	for x := 0; x < dotsX; x++ {
+		if counts[x] == 0 {
+			continue
+		}
+		y := toY(sums[x] / float64(counts[x]))
+		setDot(data, x, y)
+		// Join the dots of consecutive columns
+		if previous >= 0 {
+			for step := previous; step != y; {
+				if step < y {
+					step++
+				} else {
+					step--
+				}
+				setDot(data, x, step)
+			}
+		}
+		previous = y
+	}
+
+	drawLevel := func(value float64, levelColor string) {
+		y := toY(value)
+		for x := 0; x < dotsX; x += 2 {
+			setDot(levels, x, y)
+			row := dotsY - 1 - y
+			levelColors[row/4][x/2] = levelColor
+		}
+	}
+	if thresholds.HasWarn {
+		drawLevel(thresholds.Warning, ansiYellow)
+	}
+	if thresholds.HasCrit {
+		drawLevel(thresholds.Critical, ansiRed)
+	}
+
+	labelWidth := 0
+	labels := make([]string, height)
+	for row := range labels {
+		if row == 0 || row == height-1 || row == height/2 {
+			value := high - (high-low)*float64(row)/float64(height-1)
+			labels[row] = strconv.FormatFloat(value, 'g', 4, 64)
+		}
+		if len(labels[row]) > labelWidth {
+			labelWidth = len(labels[row])
+		}
+	}
+
+	var builder strings.Builder
+	for row := 0; row < height; row++ {
+		builder.WriteString(fmt.Sprintf("%*s ┤", labelWidth, labels[row]))
+		for col := 0; col < width; col++ {
+			cell := string(rune(0x2800) | data[row][col] | levels[row][col])
+			if data[row][col] == 0 && levels[row][col] != 0 && color {
+				cell = levelColors[row][col] + cell + ansiReset
+			}
+			builder.WriteString(cell)
+		}
+		builder.WriteString("\n")
+	}
+	startLabel := start.Format("15:04")
+	endLabel := end.Format("15:04")
+	if span > 24*time.Hour {
+		startLabel, endLabel = start.Format("01-02 15:04"), end.Format("01-02 15:04")
+	}
+	builder.WriteString(fmt.Sprintf("%*s └%s\n", labelWidth, "", strings.Repeat("─", width)))
+	builder.WriteString(fmt.Sprintf("%*s  %s%*s\n", labelWidth, "", startLabel, width-len(startLabel), endLabel))
+
+	var legend []string
+	if thresholds.HasWarn {
+		legend = append(legend, fmt.Sprintf("warning %s", strconv.FormatFloat(thresholds.Warning, 'f', -1, 64)))
+	}
+	if thresholds.HasCrit {
+		legend = append(legend, fmt.Sprintf("critical %s", strconv.FormatFloat(thresholds.Critical, 'f', -1, 64)))
+	}
+	if len(legend) > 0 {
+		builder.WriteString(fmt.Sprintf("%*s  ⠒ %s\n", labelWidth, "", strings.Join(legend, ", ")))
+	}
+	return builder.String()
+}
+
+// renderSparkline draws the points on a single line of block characters.
+// Blocks over the warning or critical threshold are yellow or red in a
+// terminal, and marked with W or C on the line below otherwise.
+func renderSparkline(points []metricPoint, thresholds metricThresholds, width int, color bool) string {
+	blocks := []rune("▁▂▃▄▅▆▇█")
+	if len(points) < width {
+		width = len(points)
+	}
+
+	values := make([]float64, width)
+	low, high := math.Inf(1), math.Inf(-1)
+	for i := range values {
+		from, to := i*len(points)/width, (i+1)*len(points)/width
+		sum := 0.0
+		for _, p := range points[from:to] {
+			sum += p.Value
+		}
+		values[i] = sum / float64(to-from)
+		low, high = math.Min(low, values[i]), math.Max(high, values[i])
+	}
+
+	var line, marks strings.Builder
+	marked := false
+	for _, v := range values {
+		level := 0
+		if high > low {
+			level = int((v - low) / (high - low) * float64(len(blocks)-1))
+		}
+		block, mark, blockColor := string(blocks[level]), " ", ""
+		switch {
+		case thresholds.HasCrit && thresholdExceeded(v, thresholds.Critical, thresholds):
+			mark, blockColor = "C", ansiRed
+		case thresholds.HasWarn && thresholdExceeded(v, thresholds.Warning, thresholds):
+			mark, blockColor = "W", ansiYellow
+		}
+		if color && blockColor != "" {
+			block = blockColor + block + ansiReset
+		}
+		marked = marked || mark != " "
+		line.WriteString(block)
+		marks.WriteString(mark)
+	}
+	line.WriteString("\n")
+	if marked && !color {
+		line.WriteString(strings.TrimRight(marks.String(), " ") + "\n")
+	}
+
+	var legend []string
+	if thresholds.HasWarn {
+		legend = append(legend, fmt.Sprintf("warning %s", strconv.FormatFloat(thresholds.Warning, 'f', -1, 64)))
+	}
+	if thresholds.HasCrit {
+		legend = append(legend, fmt.Sprintf("critical %s", strconv.FormatFloat(thresholds.Critical, 'f', -1, 64)))
+	}
+	if len(legend) > 0 {
+		line.WriteString("thresholds: " + strings.Join(legend, ", ") + "\n")
+	}
+	return line.String()
+}
+
+// thresholdExceeded reports whether a value is past a threshold. Values are
+// expected to grow towards the critical threshold, unless the warning
+// threshold is above it.
+func thresholdExceeded(value, threshold float64, thresholds metricThresholds) bool {
+	if thresholds.HasWarn && thresholds.HasCrit && thresholds.Warning > thresholds.Critical {
+		return value <= threshold
+	}
+	return value >= threshold
+}
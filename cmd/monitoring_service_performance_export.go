@@ -0,0 +1,364 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metricPoint is a single value of a metric, flattened from a metric version.
+type metricPoint struct {
+	Time   time.Time
+	Metric string
+	Value  float64
+	Unit   string
+	Warn   string
+	Crit   string
+}
+
+// perfdataPattern matches one Nagios performance data item:
+// 'label'=value[UOM];[warn];[crit];[min];[max]
+var perfdataPattern = regexp.MustCompile(`('[^']+'|[^\s=]+)=([-+]?[0-9.]+(?:[eE][-+]?[0-9]+)?)([a-zA-Z%/]*)((?:;[^;\s]*){0,4})`)
+
+// metricPoints flattens a metric version returned by the metric history into
+// one point per metric. Metrics can be given as Nagios performance data or as
+// a list or map of metric objects.
+func metricPoints(version interface{}) []metricPoint {
+	v, ok := version.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	date := versionTime(v)
+
+	var points []metricPoint
+	for _, key := range []string{"metrics", "perfdata", "performanceData", "data"} {
+		switch metrics := v[key].(type) {
+		case string:
+			points = append(points, parsePerfdata(metrics, date)...)
+		case []interface{}:
+			for _, m := range metrics {
+				if object, ok := m.(map[string]interface{}); ok {
+					if point, ok := metricObjectPoint("", object, date); ok {
+						points = append(points, point)
+					}
+				}
+			}
+		case map[string]interface{}:
+			names := make([]string, 0, len(metrics))
+			for name := range metrics {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				switch m := metrics[name].(type) {
+				case float64:
+					points = append(points, metricPoint{Time: date, Metric: name, Value: m})
+				case map[string]interface{}:
+					if point, ok := metricObjectPoint(name, m, date); ok {
+						points = append(points, point)
+					}
+				}
+			}
+		}
+		if len(points) > 0 {
+			break
+		}
+	}
+
+	// A version can also be a single metric
+	if len(points) == 0 {
+		if point, ok := metricObjectPoint("", v, date); ok {
+			points = append(points, point)
+		}
+	}
+	return points
+}
+
+func metricObjectPoint(name string, m map[string]interface{}, date time.Time) (metricPoint, bool) {
+	point := metricPoint{Time: date, Metric: name}
+	if t := versionTime(m); !t.IsZero() {
+		point.Time = t
+	}
+	for _, key := range []string{"name", "label", "metricName", "metric"} {
+		if s, ok := m[key].(string); ok && s != "" {
+			point.Metric = s
+			break
+		}
+	}
+	value, ok := numberField(m, "value")
+	if !ok || point.Metric == "" {
+		return point, false
+	}
+	point.Value = value
+	point.Unit = stringField(m, "unit", "uom")
+	point.Warn = stringField(m, "warn", "warning")
+	point.Crit = stringField(m, "crit", "critical")
+	return point, true
+}
+
+func parsePerfdata(perfdata string, date time.Time) []metricPoint {
+	var points []metricPoint
+	for _, match := range perfdataPattern.FindAllStringSubmatch(perfdata, -1) {
+		value, err := strconv.ParseFloat(match[2], 64)
+		if err != nil {
+			continue
+		}
+		point := metricPoint{
+			Time:   date,
+			Metric: strings.Trim(match[1], "'"),
+			Value:  value,
+			Unit:   match[3],
+		}
+		thresholds := strings.Split(strings.TrimPrefix(match[4], ";"), ";")
+		if len(thresholds) > 0 {
+			point.Warn = thresholds[0]
+		}
+		if len(thresholds) > 1 {
+			point.Crit = thresholds[1]
+		}
+		points = append(points, point)
+	}
+	return points
+}
+
+// versionTime returns the date of a metric version, given as a Unix timestamp
+// in seconds or milliseconds or as an RFC3339 date.
+func versionTime(v map[string]interface{}) time.Time {
+	for _, key := range []string{"date", "timestamp", "time", "createdAt", "checkedAt"} {
+		switch value := v[key].(type) {
+		case float64:
+			if value > 1e12 {
+				return time.Unix(0, int64(value)*int64(time.Millisecond))
+			}
+			return time.Unix(int64(value), 0)
+		case string:
+			if t, err := parseTime(value, time.Now()); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+func numberField(m map[string]interface{}, keys ...string) (float64, bool) {
+	for _, key := range keys {
+		switch value := m[key].(type) {
+		case float64:
+			return value, true
+		case string:
+			if f, err := strconv.ParseFloat(value, 64); err == nil {
+				return f, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func stringField(m map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if value, ok := m[key]; ok && value != nil {
+			return fmt.Sprintf("%v", value)
+		}
+	}
+	return ""
+}
+
+// thresholdValue returns the upper bound of a Nagios threshold range such as
+// "80", "10:80" or "~:80".
+func thresholdValue(threshold string) (float64, bool) {
+	if i := strings.LastIndex(threshold, ":"); i >= 0 {
+		threshold = threshold[i+1:]
+	}
+	value, err := strconv.ParseFloat(strings.TrimPrefix(threshold, "@"), 64)
+	if err != nil || math.IsNaN(value) {
+		return 0, false
+	}
+	return value, true
+}
+
+// metricExporter writes metric points in an export format.
+type metricExporter interface {
+	Write(point metricPoint) error
+	Close() error
+}
+
+func newMetricExporter(format string, w io.Writer, serviceID string) (metricExporter, error) {
+	switch format {
+	case "csv":
+		writer := csv.NewWriter(w)
+		if err := writer.Write([]string{"timestamp", "metric", "value", "unit", "warn", "crit"}); err != nil {
+			return nil, err
+		}
+		return &csvMetricExporter{writer: writer}, nil
+	case "influx":
+		return &influxMetricExporter{writer: bufio.NewWriter(w), serviceID: serviceID}, nil
+	case "openmetrics":
+		return newOpenMetricsExporter(w, serviceID), nil
+	}
+	return nil, fmt.Errorf("unsupported export format: %s. Supported formats are csv, influx and openmetrics", format)
+}
+
+type csvMetricExporter struct {
+	writer *csv.Writer
+}
+
+func (e *csvMetricExporter) Write(point metricPoint) error {
+	return e.writer.Write([]string{
+		point.Time.Format(time.RFC3339),
+		point.Metric,
+		strconv.FormatFloat(point.Value, 'f', -1, 64),
+		point.Unit,
+		point.Warn,
+		point.Crit,
+	})
+}
+
+func (e *csvMetricExporter) Close() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// influxMetricExporter writes the InfluxDB line protocol, one line per point
+// with the value and numeric thresholds as fields.
+type influxMetricExporter struct {
+	writer    *bufio.Writer
+	serviceID string
+}
+
+var influxTagEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+
+func (e *influxMetricExporter) Write(point metricPoint) error {
+	line := fmt.Sprintf("rtms_performance,service_id=%s,metric=%s", influxTagEscaper.Replace(e.serviceID), influxTagEscaper.Replace(point.Metric))
+	if point.Unit != "" {
+		line += ",unit=" + influxTagEscaper.Replace(point.Unit)
+	}
+	line += " value=" + strconv.FormatFloat(point.Value, 'f', -1, 64)
+	if warn, ok := thresholdValue(point.Warn); ok {
+		line += ",warn=" + strconv.FormatFloat(warn, 'f', -1, 64)
+	}
+	if crit, ok := thresholdValue(point.Crit); ok {
+		line += ",crit=" + strconv.FormatFloat(crit, 'f', -1, 64)
+	}
+	_, err := fmt.Fprintf(e.writer, "%s %d\n", line, point.Time.UnixNano())
+	return err
+}
+
+func (e *influxMetricExporter) Close() error {
+	return e.writer.Flush()
+}
+
+// openMetricsExporter streams the values as they come. OpenMetrics requires
+// the samples of a family to be contiguous, so the thresholds are kept and
+// written at the end, only when they change.
+type openMetricsExporter struct {
+	writer     *bufio.Writer
+	serviceID  string
+	thresholds map[string][]string
+	last       map[string]string
+}
+
+func newOpenMetricsExporter(w io.Writer, serviceID string) *openMetricsExporter {
+	e := &openMetricsExporter{
+		writer:     bufio.NewWriter(w),
+		serviceID:  serviceID,
+		thresholds: make(map[string][]string),
+		last:       make(map[string]string),
+	}
+	e.writer.WriteString("# TYPE rtms_performance gauge\n# HELP rtms_performance Metric values of a monitoring service.\n")
+	return e
+}
+
+func (e *openMetricsExporter) labels(point metricPoint) string {
+	labels := fmt.Sprintf(`service_id="%s",metric="%s"`, escapeLabelValue(e.serviceID), escapeLabelValue(point.Metric))
+	if point.Unit != "" {
+		labels += fmt.Sprintf(`,unit="%s"`, escapeLabelValue(point.Unit))
+	}
+	return labels
+}
+
+func (e *openMetricsExporter) Write(point metricPoint) error {
+	labels := e.labels(point)
+	timestamp := strconv.FormatFloat(float64(point.Time.UnixNano())/1e9, 'f', -1, 64)
+	if _, err := fmt.Fprintf(e.writer, "rtms_performance{%s} %s %s\n", labels, strconv.FormatFloat(point.Value, 'f', -1, 64), timestamp); err != nil {
+		return err
+	}
+
+	for family, threshold := range map[string]string{"rtms_performance_warning": point.Warn, "rtms_performance_critical": point.Crit} {
+		value, ok := thresholdValue(threshold)
+		if !ok {
+			continue
+		}
+		formatted := strconv.FormatFloat(value, 'f', -1, 64)
+		key := family + "{" + labels + "}"
+		if e.last[key] == formatted {
+			continue
+		}
+		e.last[key] = formatted
+		e.thresholds[family] = append(e.thresholds[family], fmt.Sprintf("%s %s %s\n", key, formatted, timestamp))
+	}
+	return nil
+}
+
+func (e *openMetricsExporter) Close() error {
+	for _, family := range []string{"rtms_performance_warning", "rtms_performance_critical"} {
+		if len(e.thresholds[family]) == 0 {
+			continue
+		}
+		fmt.Fprintf(e.writer, "# TYPE %s gauge\n", family)
+		for _, sample := range e.thresholds[family] {
+			e.writer.WriteString(sample)
+		}
+	}
+	e.writer.WriteString("# EOF\n")
+	return e.writer.Flush()
+}
+
+// exportMetricHistory streams the metric history of a service across all
+// pages and writes every point in the export format. CSV is written as the
+// points come; influx and openmetrics samples must have increasing timestamps,
+// so their points are sorted by time first, whatever the listing order.
+func exportMetricHistory(serviceID string, params map[string]string, format string) error {
+	exporter, err := newMetricExporter(format, os.Stdout, serviceID)
+	if err != nil {
+		return err
+	}
+
+	var points []metricPoint
+	dataChan, errChan := client.StreamData(fmt.Sprintf("/monitoringServices/%s/metricHistory", serviceID), params, batchSize)
+	for item := range dataChan {
+		for _, point := range metricPoints(item) {
+			if format != "csv" {
+				points = append(points, point)
+				continue
+			}
+			if err := exporter.Write(point); err != nil {
+				return err
+			}
+		}
+	}
+	if err := <-errChan; err != nil {
+		return fmt.Errorf("erreur lors de la récupération de l'historique des métriques : %w", err)
+	}
+
+	sort.SliceStable(points, func(i, j int) bool {
+		// The samples of a metric are also kept together in OpenMetrics
+		if format == "openmetrics" && points[i].Metric != points[j].Metric {
+			return points[i].Metric < points[j].Metric
+		}
+		return points[i].Time.Before(points[j].Time)
+	})
+	for _, point := range points {
+		if err := exporter.Write(point); err != nil {
+			return err
+		}
+	}
+	return exporter.Close()
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParsePerfdata(t *testing.T) {
+	date := time.Date(2026, 9, 1, 8, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		perfdata string
+		want     []metricPoint
+	}{
+		{
+			name:     "value only",
+			perfdata: "load1=0.42",
+			want:     []metricPoint{{Time: date, Metric: "load1", Value: 0.42}},
+		},
+		{
+			name:     "unit and thresholds",
+			perfdata: "rta=12.5ms;100;500;0; pl=0%;20;60;;",
+			want: []metricPoint{
+				{Time: date, Metric: "rta", Value: 12.5, Unit: "ms", Warn: "100", Crit: "500"},
+				{Time: date, Metric: "pl", Value: 0, Unit: "%", Warn: "20", Crit: "60"},
+			},
+		},
+		{
+			name:     "quoted label with spaces",
+			perfdata: "'/var used'=81.2%;80:;90:",
+			want:     []metricPoint{{Time: date, Metric: "/var used", Value: 81.2, Unit: "%", Warn: "80:", Crit: "90:"}},
+		},
+		{
+			name:     "negative and exponent values",
+			perfdata: "offset=-0.003s;1;2 rate=1.5e3B/s",
+			want: []metricPoint{
+				{Time: date, Metric: "offset", Value: -0.003, Unit: "s", Warn: "1", Crit: "2"},
+				{Time: date, Metric: "rate", Value: 1500, Unit: "B/s"},
+			},
+		},
+		{
+			name:     "unparsable value",
+			perfdata: "broken=. ok=1",
+			want:     []metricPoint{{Time: date, Metric: "ok", Value: 1}},
+		},
+		{
+			name:     "no perfdata",
+			perfdata: "OK - all good",
+			want:     nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parsePerfdata(tt.perfdata, date); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePerfdata(%q) = %+v, want %+v", tt.perfdata, got, tt.want)
+			}
+		})
+	}
+}
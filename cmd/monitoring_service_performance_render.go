@@ -0,0 +1,391 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fogleman/gg"
+	"github.com/spf13/cobra"
+)
+
+// graphSeries is the history of one metric of a graph.
+type graphSeries struct {
+	Metric     string
+	Points     []metricPoint
+	Thresholds metricThresholds
+}
+
+// graphImage combines a graph configuration with the metric history to draw.
+type graphImage struct {
+	Title  string
+	Unit   string
+	Start  time.Time
+	End    time.Time
+	Series []graphSeries
+}
+
+// graphCanvas is implemented by the SVG and PNG backends.
+type graphCanvas interface {
+	Rect(x, y, w, h float64, fill, stroke string)
+	Polyline(points [][2]float64, stroke string, width float64, dashed bool)
+	Text(x, y float64, text, anchor, color string)
+}
+
+var graphPalette = []string{"#1f77b4", "#2ca02c", "#9467bd", "#8c564b", "#17becf", "#e377c2"}
+
+const (
+	graphWarningColor  = "#e6a700"
+	graphCriticalColor = "#d62728"
+)
+
+func init() {
+	// Render graph
+	renderGraphCmd := &cobra.Command{
+		Use:   "render [service-id]",
+		Short: "Render a graph of a monitoring service to an SVG or PNG file",
+		Long: `Render a graph of a monitoring service to an SVG or PNG file, combining its
+graph configuration with the metric history. Images are rendered locally and
+can be embedded in incident reports and tickets.`,
+		Args: cobra.ExactArgs(1),
+		RunE: renderGraph,
+	}
+	renderGraphCmd.Flags().String("graph", "", "Label of the graph configuration to render (required when the service has several graphs)")
+	renderGraphCmd.Flags().StringP("output", "o", "", "Output file, .svg or .png")
+	renderGraphCmd.Flags().String("since", "24h", "Duration of the period to render, up to now")
+	renderGraphCmd.Flags().Int("width", 900, "Width of the image in pixels")
+	renderGraphCmd.Flags().Int("height", 400, "Height of the image in pixels")
+	renderGraphCmd.MarkFlagRequired("output")
+	monitoringServicePerformanceCmd.AddCommand(renderGraphCmd)
+}
+
+func renderGraph(cmd *cobra.Command, args []string) error {
+	graph, _ := cmd.Flags().GetString("graph")
+	output, _ := cmd.Flags().GetString("output")
+	sinceFlag, _ := cmd.Flags().GetString("since")
+	width, _ := cmd.Flags().GetInt("width")
+	height, _ := cmd.Flags().GetInt("height")
+
+	since, err := parseDuration(sinceFlag)
+	if err != nil {
+		return err
+	}
+	graphs, err := findGraphConfigurations(args[0], graph)
+	if err != nil {
+		return err
+	}
+	if len(graphs) > 1 {
+		labels := make([]string, len(graphs))
+		for i, g := range graphs {
+			labels[i] = resourceName(g)
+		}
+		return fmt.Errorf("several graphs match, use --graph with one of: %s", strings.Join(labels, ", "))
+	}
+	end := time.Now()
+	image, err := newGraphImage(args[0], graphs[0], end.Add(-since), end)
+	if err != nil {
+		return err
+	}
+	content, err := image.Encode(strings.TrimPrefix(strings.ToLower(filepath.Ext(output)), "."), width, height)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(output, content, 0644); err != nil {
+		return fmt.Errorf("failed to write image: %v", err)
+	}
+	fmt.Printf("Graph %q written to %s\n", image.Title, output)
+	return nil
+}
+
+// findGraphConfigurations returns the graph configurations of a service whose
+// label contains label, or only the one whose label is label. All the graphs
+// are returned when label is empty.
+func findGraphConfigurations(serviceID, label string) ([]map[string]interface{}, error) {
+	params := make(map[string]string)
+	if label != "" {
+		params["label"] = label
+	}
+	response, err := client.GetGraphConfigurations(serviceID, params)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching graph configurations: %w", err)
+	}
+	data, err := decodeData(response)
+	if err != nil {
+		return nil, err
+	}
+	list, _ := data.([]interface{})
+
+	var graphs []map[string]interface{}
+	for _, item := range list {
+		graph, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name := resourceName(graph)
+		if strings.EqualFold(name, label) {
+			graphs = []map[string]interface{}{graph}
+			break
+		}
+		if label == "" || strings.Contains(strings.ToLower(name), strings.ToLower(label)) {
+			graphs = append(graphs, graph)
+		}
+	}
+	if len(graphs) == 0 && label == "" {
+		return nil, fmt.Errorf("service %s has no graph configuration", serviceID)
+	}
+	if len(graphs) == 0 {
+		return nil, fmt.Errorf("no graph configuration matching %q for service %s", label, serviceID)
+	}
+	return graphs, nil
+}
+
+// newGraphImage fetches the history of the metrics of a graph over the period.
+func newGraphImage(serviceID string, graph map[string]interface{}, start, end time.Time) (*graphImage, error) {
+	metrics := graphMetricNames(graph)
+	if len(metrics) == 0 {
+		return nil, fmt.Errorf("graph %q has no metric", resourceName(graph))
+	}
+
+	image := &graphImage{
+		Title: fmt.Sprintf("%s - service %s", resourceName(graph), serviceID),
+		Start: start,
+		End:   end,
+	}
+	for _, metric := range metrics {
+		points, err := fetchMetricPoints(serviceID, metric, start, end)
+		if err != nil {
+			return nil, err
+		}
+		var thresholds metricThresholds
+		graphThresholds(graph, metric, &thresholds)
+		if len(points) > 0 {
+			last := points[len(points)-1]
+			if thresholds.Unit == "" {
+				thresholds.Unit = last.Unit
+			}
+			if !thresholds.HasWarn {
+				thresholds.Warning, thresholds.HasWarn = thresholdValue(last.Warn)
+			}
+			if !thresholds.HasCrit {
+				thresholds.Critical, thresholds.HasCrit = thresholdValue(last.Crit)
+			}
+		}
+		if image.Unit == "" {
+			image.Unit = thresholds.Unit
+		}
+		image.Series = append(image.Series, graphSeries{Metric: metric, Points: points, Thresholds: thresholds})
+	}
+	return image, nil
+}
+
+// graphMetricNames returns the names of the metrics drawn by a graph.
+func graphMetricNames(graph map[string]interface{}) []string {
+	var names []string
+	switch metrics := graph["metrics"].(type) {
+	case []interface{}:
+		for _, m := range metrics {
+			switch value := m.(type) {
+			case string:
+				names = append(names, value)
+			case map[string]interface{}:
+				if name := stringField(value, "name", "metric", "label"); name != "" {
+					names = append(names, name)
+				}
+			}
+		}
+	case map[string]interface{}:
+		for name := range metrics {
+			names = append(names, name)
+		}
+		// Keep the colours and the legend stable between runs
+		sort.Strings(names)
+	}
+	return names
+}
+
+// Encode renders the image as svg or png.
+func (g *graphImage) Encode(format string, width, height int) ([]byte, error) {
+	if width < 200 || height < 150 {
+		return nil, fmt.Errorf("the image must be at least 200x150 pixels")
+	}
+	switch format {
+	case "svg":
+		canvas := &svgCanvas{}
+		canvas.builder.WriteString(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n", width, height, width, height))
+		g.draw(canvas, float64(width), float64(height))
+		canvas.builder.WriteString("</svg>\n")
+		return []byte(canvas.builder.String()), nil
+	case "png":
+		canvas := &pngCanvas{context: gg.NewContext(width, height)}
+		g.draw(canvas, float64(width), float64(height))
+		var buf bytes.Buffer
+		if err := canvas.context.EncodePNG(&buf); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, fmt.Errorf("unsupported image format: %q. Supported formats are svg and png", format)
+}
+
+// draw lays out the graph: title, axes with grid, series, threshold lines and
+// legend.
+func (g *graphImage) draw(c graphCanvas, width, height float64) {
+	const left, right, top, bottom = 70.0, 20.0, 40.0, 60.0
+	plotW, plotH := width-left-right, height-top-bottom
+
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, s := range g.Series {
+		for _, p := range s.Points {
+			low, high = math.Min(low, p.Value), math.Max(high, p.Value)
+		}
+		if s.Thresholds.HasWarn {
+			low, high = math.Min(low, s.Thresholds.Warning), math.Max(high, s.Thresholds.Warning)
+		}
+		if s.Thresholds.HasCrit {
+			low, high = math.Min(low, s.Thresholds.Critical), math.Max(high, s.Thresholds.Critical)
+		}
+	}
+	if math.IsInf(low, 0) {
+		low, high = 0, 1
+	}
+	if low > 0 {
+		low = 0
+	}
+	if high == low {
+		high = low + 1
+	}
+	high += (high - low) * 0.05
+
+	toX := func(t time.Time) float64 {
+		return left + float64(t.Sub(g.Start))/float64(g.End.Sub(g.Start))*plotW
+	}
+	toY := func(v float64) float64 {
+		return top + plotH - (v-low)/(high-low)*plotH
+	}
+
+	c.Rect(0, 0, width, height, "#ffffff", "")
+	c.Text(width/2, 24, g.Title, "middle", "#000000")
+	c.Rect(left, top, plotW, plotH, "#ffffff", "#cccccc")
+
+	for i := 0; i <= 5; i++ {
+		value := low + (high-low)*float64(i)/5
+		y := toY(value)
+		c.Polyline([][2]float64{{left, y}, {left + plotW, y}}, "#eeeeee", 1, false)
+		c.Text(left-6, y+4, strconv.FormatFloat(value, 'g', 4, 64)+g.Unit, "end", "#333333")
+	}
+	layout := "15:04"
+	if g.End.Sub(g.Start) > 24*time.Hour {
+		layout = "01-02 15:04"
+	}
+	for i := 0; i <= 6; i++ {
+		t := g.Start.Add(time.Duration(float64(g.End.Sub(g.Start)) * float64(i) / 6))
+		x := toX(t)
+		c.Polyline([][2]float64{{x, top}, {x, top + plotH}}, "#eeeeee", 1, false)
+		c.Text(x, top+plotH+16, t.Format(layout), "middle", "#333333")
+	}
+
+	legendX := left
+	for i, s := range g.Series {
+		color := graphPalette[i%len(graphPalette)]
+		var line [][2]float64
+		for _, p := range s.Points {
+			line = append(line, [2]float64{toX(p.Time), toY(p.Value)})
+		}
+		if len(line) > 1 {
+			c.Polyline(line, color, 1.5, false)
+		}
+
+		for _, level := range []struct {
+			set   bool
+			value float64
+			color string
+		}{{s.Thresholds.HasWarn, s.Thresholds.Warning, graphWarningColor}, {s.Thresholds.HasCrit, s.Thresholds.Critical, graphCriticalColor}} {
+			if level.set {
+				y := toY(level.value)
+				c.Polyline([][2]float64{{left, y}, {left + plotW, y}}, level.color, 1, true)
+			}
+		}
+
+		legend := s.Metric
+		if len(s.Points) > 0 {
+			legend += " (" + summarizeMetric(s.Points, s.Thresholds.Unit) + ")"
+		}
+		c.Rect(legendX, height-22, 10, 10, color, "")
+		c.Text(legendX+14, height-13, legend, "start", "#333333")
+		legendX += 22 + float64(len(legend))*7
+	}
+}
+
+type svgCanvas struct {
+	builder strings.Builder
+}
+
+func (c *svgCanvas) Rect(x, y, w, h float64, fill, stroke string) {
+	if stroke == "" {
+		stroke = "none"
+	}
+	c.builder.WriteString(fmt.Sprintf(`<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s" stroke="%s"/>`+"\n", x, y, w, h, fill, stroke))
+}
+
+func (c *svgCanvas) Polyline(points [][2]float64, stroke string, width float64, dashed bool) {
+	coords := make([]string, len(points))
+	for i, p := range points {
+		coords[i] = fmt.Sprintf("%.1f,%.1f", p[0], p[1])
+	}
+	dash := ""
+	if dashed {
+		dash = ` stroke-dasharray="6,4"`
+	}
+	c.builder.WriteString(fmt.Sprintf(`<polyline fill="none" stroke="%s" stroke-width="%.1f"%s points="%s"/>`+"\n", stroke, width, dash, strings.Join(coords, " ")))
+}
+
+func (c *svgCanvas) Text(x, y float64, text, anchor, color string) {
+	c.builder.WriteString(fmt.Sprintf(`<text x="%.1f" y="%.1f" text-anchor="%s" fill="%s">%s</text>`+"\n", x, y, anchor, color, html.EscapeString(text)))
+}
+
+type pngCanvas struct {
+	context *gg.Context
+}
+
+func (c *pngCanvas) Rect(x, y, w, h float64, fill, stroke string) {
+	c.context.DrawRectangle(x, y, w, h)
+	c.context.SetHexColor(fill)
+	if stroke == "" {
+		c.context.Fill()
+		return
+	}
+	c.context.FillPreserve()
+	c.context.SetHexColor(stroke)
+	c.context.SetLineWidth(1)
+	c.context.Stroke()
+}
+
+func (c *pngCanvas) Polyline(points [][2]float64, stroke string, width float64, dashed bool) {
+	if len(points) == 0 {
+		return
+	}
+	c.context.MoveTo(points[0][0], points[0][1])
+	for _, p := range points[1:] {
+		c.context.LineTo(p[0], p[1])
+	}
+	if dashed {
+		c.context.SetDash(6, 4)
+	}
+	c.context.SetHexColor(stroke)
+	c.context.SetLineWidth(width)
+	c.context.Stroke()
+	c.context.SetDash()
+}
+
+func (c *pngCanvas) Text(x, y float64, text, anchor, color string) {
+	align := map[string]float64{"start": 0, "middle": 0.5, "end": 1}[anchor]
+	c.context.SetHexColor(color)
+	c.context.DrawStringAnchored(text, x, y, align, 0)
+}
@@ -56,9 +56,9 @@ func init() {
 		RunE:  createMonitoringService,
 	}
 	createMonitoringServiceCmd.Flags().String("name", "", "Monitoring service name")
-	createMonitoringServiceCmd.Flags().Int("appliance", 0, "Appliance ID")
-	createMonitoringServiceCmd.Flags().Int("host", 0, "Host ID")
-	createMonitoringServiceCmd.Flags().Int("template", 0, "Template ID")
+	createMonitoringServiceCmd.Flags().String("appliance", "", "Appliance ID or name")
+	createMonitoringServiceCmd.Flags().String("host", "", "Host ID or name")
+	createMonitoringServiceCmd.Flags().String("template", "", "Template ID or name")
 	createMonitoringServiceCmd.MarkFlagRequired("name")
 	createMonitoringServiceCmd.MarkFlagRequired("appliance")
 	createMonitoringServiceCmd.MarkFlagRequired("host")
@@ -91,9 +91,9 @@ func init() {
 		RunE:  updateMonitoringService,
 	}
 	updateMonitoringServiceCmd.Flags().String("name", "", "Monitoring service name")
-	updateMonitoringServiceCmd.Flags().Int("appliance", 0, "Appliance ID")
-	updateMonitoringServiceCmd.Flags().Int("host", 0, "Host ID")
-	updateMonitoringServiceCmd.Flags().Int("template", 0, "Template ID")
+	updateMonitoringServiceCmd.Flags().String("appliance", "", "Appliance ID or name")
+	updateMonitoringServiceCmd.Flags().String("host", "", "Host ID or name")
+	updateMonitoringServiceCmd.Flags().String("template", "", "Template ID or name")
 	monitoringServicesCmd.AddCommand(updateMonitoringServiceCmd)
 
 	// Get monitoring service templates
@@ -114,16 +114,30 @@ func init() {
 	}
 	getMonitoringServicesStatsCmd.Flags().Int("host-id", 0, "Show stats of filtered monitoring services by host")
 	getMonitoringServicesStatsCmd.Flags().Int("appliance-id", 0, "Show stats of filtered monitoring services by appliance")
+	addWatchFlag(getMonitoringServicesStatsCmd)
 	monitoringServicesCmd.AddCommand(getMonitoringServicesStatsCmd)
 }
 
 func createMonitoringService(cmd *cobra.Command, args []string) error {
 	name, _ := cmd.Flags().GetString("name")
-	appliance, _ := cmd.Flags().GetInt("appliance")
-	host, _ := cmd.Flags().GetInt("host")
-	template, _ := cmd.Flags().GetInt("template")
+	applianceRef, _ := cmd.Flags().GetString("appliance")
+	hostRef, _ := cmd.Flags().GetString("host")
+	templateRef, _ := cmd.Flags().GetString("template")
 	format, _ := cmd.Flags().GetString("format")
 
+	appliance, err := resolveID(resolveAppliances, applianceRef)
+	if err != nil {
+		return err
+	}
+	host, err := resolveID(resolveHostIDs, hostRef)
+	if err != nil {
+		return err
+	}
+	template, err := resolveID(resolveMonitoringServiceTemplates, templateRef)
+	if err != nil {
+		return err
+	}
+
 	serviceData := map[string]interface{}{
 		"name":      name,
 		"appliance": appliance,
@@ -178,14 +192,22 @@ func updateMonitoringService(cmd *cobra.Command, args []string) error {
 	if name, _ := cmd.Flags().GetString("name"); name != "" {
 		serviceData["name"] = name
 	}
-	if appliance, _ := cmd.Flags().GetInt("appliance"); appliance != 0 {
-		serviceData["appliance"] = appliance
-	}
-	if host, _ := cmd.Flags().GetInt("host"); host != 0 {
-		serviceData["host"] = host
+	references := []struct {
+		flag    string
+		resolve func([]string) ([]int, error)
+	}{
+		{"appliance", resolveAppliances},
+		{"host", resolveHostIDs},
+		{"template", resolveMonitoringServiceTemplates},
 	}
-	if template, _ := cmd.Flags().GetInt("template"); template != 0 {
-		serviceData["template"] = template
+	for _, r := range references {
+		if ref, _ := cmd.Flags().GetString(r.flag); ref != "" {
+			id, err := resolveID(r.resolve, ref)
+			if err != nil {
+				return err
+			}
+			serviceData[r.flag] = id
+		}
 	}
 
 	response, err := client.UpdateMonitoringService(args[0], serviceData)
@@ -244,6 +266,20 @@ func getMonitoringServicesStats(cmd *cobra.Command, args []string) error {
 		params["applianceId"] = fmt.Sprintf("%d", applianceID)
 	}
 
+	interval, err := watchInterval(cmd)
+	if err != nil {
+		return err
+	}
+	if interval > 0 {
+		return runWatch(cmd, interval, func() (interface{}, error) {
+			response, err := client.GetMonitoringServicesStats(cloudTempleID, params)
+			if err != nil {
+				return nil, err
+			}
+			return decodeData(response)
+		})
+	}
+
 	response, err := client.GetMonitoringServicesStats(cloudTempleID, params)
 	if err != nil {
 		return err
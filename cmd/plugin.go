@@ -0,0 +1,292 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Nagios plugin return codes
+const (
+	pluginOK       = 0
+	pluginWarning  = 1
+	pluginCritical = 2
+	pluginUnknown  = 3
+)
+
+const pluginServiceAnnotation = "plugin-service"
+
+var pluginStatusNames = []string{"OK", "WARNING", "CRITICAL", "UNKNOWN"}
+
+// pluginSeverity orders the return codes from the best to the worst status.
+var pluginSeverity = map[int]int{pluginOK: 0, pluginWarning: 1, pluginUnknown: 2, pluginCritical: 3}
+
+// pluginResult is the outcome of a check run with --plugin.
+type pluginResult struct {
+	Service  string
+	Status   int
+	Messages []string
+	Perfdata []string
+}
+
+// raise sets the status of the result to status if it is worse than the
+// current one and records the reason.
+func (r *pluginResult) raise(status int, message string) {
+	if pluginSeverity[status] > pluginSeverity[r.Status] {
+		r.Status = status
+	}
+	if message != "" {
+		r.Messages = append(r.Messages, message)
+	}
+}
+
+// exit prints the Nagios status line and returns the plugin return code as
+// an *ExitError, or nil when the status is OK.
+func (r *pluginResult) exit() error {
+	line := fmt.Sprintf("%s %s", r.Service, pluginStatusNames[r.Status])
+	if len(r.Messages) > 0 {
+		// The status line must fit on a single line
+		line += " - " + strings.Join(strings.Fields(strings.Join(r.Messages, ", ")), " ")
+	}
+	if len(r.Perfdata) > 0 {
+		line += " | " + strings.Join(r.Perfdata, " ")
+	}
+	fmt.Println(line)
+	if r.Status == pluginOK {
+		return nil
+	}
+	return &ExitError{Code: r.Status}
+}
+
+// addPluginFlags registers --plugin and the warning and critical thresholds
+// of the duration measured by the check. service names the check in the
+// status line of the errors reported by pluginError.
+func addPluginFlags(cmd *cobra.Command, service, threshold, warning, critical, description string) {
+	if cmd.Annotations == nil {
+		cmd.Annotations = make(map[string]string)
+	}
+	cmd.Annotations[pluginServiceAnnotation] = service
+	cmd.Flags().Bool("plugin", false, "Print a Nagios plugin status line and exit with the plugin return code")
+	cmd.Flags().String("warning-"+threshold, warning, "Warning threshold of the "+description+" in plugin mode")
+	cmd.Flags().String("critical-"+threshold, critical, "Critical threshold of the "+description+" in plugin mode")
+}
+
+// pluginMode reports whether a command runs with --plugin.
+func pluginMode(cmd *cobra.Command) bool {
+	if cmd == nil || cmd.Flags().Lookup("plugin") == nil {
+		return false
+	}
+	plugin, _ := cmd.Flags().GetBool("plugin")
+	return plugin
+}
+
+// pluginError reports the error of a command run in plugin mode as an UNKNOWN
+// status, so that Nagios does not take a broken check for a WARNING.
+func pluginError(cmd *cobra.Command, err error) error {
+	var exitErr *ExitError
+	if err == nil || errors.As(err, &exitErr) || !pluginMode(cmd) {
+		return err
+	}
+	result := &pluginResult{Service: cmd.Annotations[pluginServiceAnnotation]}
+	if args := cmd.Flags().Args(); len(args) > 0 {
+		result.Service += " " + args[0]
+	}
+	result.raise(pluginUnknown, err.Error())
+	return result.exit()
+}
+
+// pluginThresholds returns the warning and critical thresholds registered by
+// addPluginFlags.
+func pluginThresholds(cmd *cobra.Command, threshold string) (time.Duration, time.Duration, error) {
+	warningFlag, _ := cmd.Flags().GetString("warning-" + threshold)
+	criticalFlag, _ := cmd.Flags().GetString("critical-" + threshold)
+	warning, err := parseDuration(warningFlag)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid warning threshold: %w", err)
+	}
+	critical, err := parseDuration(criticalFlag)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid critical threshold: %w", err)
+	}
+	if critical < warning {
+		return 0, 0, fmt.Errorf("critical threshold must not be lower than the warning threshold")
+	}
+	return warning, critical, nil
+}
+
+// checkThreshold raises the result when value exceeds a threshold and adds
+// the matching perfdata, in seconds.
+func (r *pluginResult) checkThreshold(label string, value, warning, critical time.Duration) {
+	switch {
+	case value >= critical:
+		r.raise(pluginCritical, fmt.Sprintf("%s %s exceeds %s", label, value.Round(time.Millisecond), critical))
+	case value >= warning:
+		r.raise(pluginWarning, fmt.Sprintf("%s %s exceeds %s", label, value.Round(time.Millisecond), warning))
+	}
+	r.Perfdata = append(r.Perfdata, fmt.Sprintf("%s=%.3fs;%.3f;%.3f;0;", label, value.Seconds(), warning.Seconds(), critical.Seconds()))
+}
+
+// runHealthPlugin runs a health endpoint check in plugin mode. The endpoint is
+// CRITICAL when it cannot be reached or reports a failed component, WARNING
+// when a component is degraded or the response time exceeds the thresholds.
+func runHealthPlugin(cmd *cobra.Command, service string, check func() ([]byte, error)) error {
+	warning, critical, err := pluginThresholds(cmd, "time")
+	if err != nil {
+		return err
+	}
+
+	result := &pluginResult{Service: service}
+	start := time.Now()
+	response, err := check()
+	elapsed := time.Since(start)
+	if err != nil {
+		result.raise(pluginCritical, err.Error())
+		return result.exit()
+	}
+
+	data, err := decodeData(response)
+	if err != nil {
+		result.raise(pluginUnknown, fmt.Sprintf("invalid response: %v", err))
+		return result.exit()
+	}
+	for _, problem := range healthProblems(data, "") {
+		result.raise(problem.status, problem.path)
+	}
+	result.checkThreshold("time", elapsed, warning, critical)
+	if result.Status == pluginOK {
+		result.Messages = append([]string{"healthy"}, result.Messages...)
+	}
+	return result.exit()
+}
+
+type healthProblem struct {
+	path   string
+	status int
+}
+
+// healthProblems walks a health response and returns the components that are
+// not healthy, identified by their path in the response.
+func healthProblems(v interface{}, path string) []healthProblem {
+	var problems []healthProblem
+	switch value := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			child := key
+			if path != "" {
+				child = path + "." + key
+			}
+			problems = append(problems, healthProblems(value[key], child)...)
+		}
+	case []interface{}:
+		for i, item := range value {
+			problems = append(problems, healthProblems(item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case string:
+		if status, ok := healthStatus(value); ok && status != pluginOK {
+			problems = append(problems, healthProblem{path: fmt.Sprintf("%s is %s", path, value), status: status})
+		}
+	case bool:
+		last := path[strings.LastIndex(path, ".")+1:]
+		switch strings.ToLower(last) {
+		case "healthy", "ok", "status", "success", "up":
+			if !value {
+				problems = append(problems, healthProblem{path: path + " is false", status: pluginCritical})
+			}
+		}
+	}
+	return problems
+}
+
+// healthStatus maps a state reported by the API to a plugin return code.
+func healthStatus(state string) (int, bool) {
+	switch strings.ToUpper(state) {
+	case "OK", "UP", "HEALTHY", "PASS":
+		return pluginOK, true
+	case "WARNING", "WARN", "DEGRADED":
+		return pluginWarning, true
+	case "CRITICAL", "KO", "DOWN", "FAIL", "FAILED", "ERROR", "UNHEALTHY":
+		return pluginCritical, true
+	case "UNKNOWN":
+		return pluginUnknown, true
+	}
+	return 0, false
+}
+
+// runApplianceHealthCheckPlugin checks the last heartbeat of an appliance: its
+// Nagios operating state and its age.
+func runApplianceHealthCheckPlugin(cmd *cobra.Command, id string) error {
+	warning, critical, err := pluginThresholds(cmd, "age")
+	if err != nil {
+		return err
+	}
+
+	result := &pluginResult{Service: "APPLIANCE " + id}
+	response, err := client.GetApplianceHealthCheck(id)
+	if err != nil {
+		result.raise(pluginUnknown, err.Error())
+		return result.exit()
+	}
+	data, err := decodeData(response)
+	if err != nil {
+		result.raise(pluginUnknown, fmt.Sprintf("invalid response: %v", err))
+		return result.exit()
+	}
+	heartbeat, ok := data.(map[string]interface{})
+	if !ok {
+		result.raise(pluginUnknown, "no heartbeat received")
+		return result.exit()
+	}
+
+	state, _ := heartbeat["nagiosOperatingState"].(string)
+	status, ok := healthStatus(state)
+	if !ok {
+		status = pluginUnknown
+	}
+	message := "no operating state"
+	if state != "" {
+		message = fmt.Sprintf("operating state %s", state)
+	}
+	if version, ok := heartbeat["applianceVersion"]; ok {
+		message += fmt.Sprintf(", version %v", version)
+	}
+	if details, ok := heartbeat["details"].(string); ok && details != "" {
+		message += ": " + details
+	}
+	result.raise(status, message)
+
+	if date, ok := heartbeatDate(heartbeat); ok {
+		result.checkThreshold("age", time.Since(date), warning, critical)
+	} else {
+		result.raise(pluginUnknown, "heartbeat date missing")
+	}
+	return result.exit()
+}
+
+// heartbeatDate returns the date of a heartbeat, given either as a timestamp
+// or as an RFC3339 date.
+func heartbeatDate(heartbeat map[string]interface{}) (time.Time, bool) {
+	for _, key := range []string{"date", "createdAt", "updatedAt", "lastHeartbeat"} {
+		switch value := heartbeat[key].(type) {
+		case float64:
+			return time.Unix(int64(value), 0), true
+		case string:
+			if date, err := time.Parse(time.RFC3339, value); err == nil {
+				return date, true
+			}
+			if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+				return time.Unix(seconds, 0), true
+			}
+		}
+	}
+	return time.Time{}, false
+}
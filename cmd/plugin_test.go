@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHealthProblems(t *testing.T) {
+	tests := []struct {
+		name     string
+		response interface{}
+		want     []healthProblem
+	}{
+		{
+			name:     "healthy",
+			response: map[string]interface{}{"status": "UP", "database": map[string]interface{}{"status": "ok"}},
+			want:     nil,
+		},
+		{
+			name: "failed and degraded components",
+			response: map[string]interface{}{
+				"status": "DEGRADED",
+				"components": map[string]interface{}{
+					"database": map[string]interface{}{"status": "DOWN"},
+					"cache":    map[string]interface{}{"status": "warn"},
+				},
+			},
+			want: []healthProblem{
+				{path: "components.cache.status is warn", status: pluginWarning},
+				{path: "components.database.status is DOWN", status: pluginCritical},
+				{path: "status is DEGRADED", status: pluginWarning},
+			},
+		},
+		{
+			name: "components in a list",
+			response: map[string]interface{}{
+				"checks": []interface{}{
+					map[string]interface{}{"name": "api", "state": "PASS"},
+					map[string]interface{}{"name": "queue", "state": "UNKNOWN"},
+				},
+			},
+			want: []healthProblem{{path: "checks[1].state is UNKNOWN", status: pluginUnknown}},
+		},
+		{
+			name: "boolean flags",
+			response: map[string]interface{}{
+				"healthy":     false,
+				"maintenance": false,
+				"storage":     map[string]interface{}{"up": false, "ok": true},
+			},
+			want: []healthProblem{
+				{path: "healthy is false", status: pluginCritical},
+				{path: "storage.up is false", status: pluginCritical},
+			},
+		},
+		{
+			name:     "unrelated strings",
+			response: map[string]interface{}{"version": "1.4.2", "message": "running"},
+			want:     nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := healthProblems(tt.response, ""); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("healthProblems() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// resolveReference returns the ID of the resource among items whose ID is ref,
+// or else whose names include ref, compared case-insensitively. It fails when
+// no resource or several resources match.
+func resolveReference(kind, ref string, items []interface{}, names func(map[string]interface{}) []string) (string, error) {
+	var matches []map[string]interface{}
+	for _, item := range items {
+		resource, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if resourceID(resource) == ref {
+			return ref, nil
+		}
+		for _, name := range names(resource) {
+			if name != "" && strings.EqualFold(name, ref) {
+				matches = append(matches, resource)
+				break
+			}
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no %s matching %q", kind, ref)
+	case 1:
+		return resourceID(matches[0]), nil
+	}
+	candidates := make([]string, len(matches))
+	for i, m := range matches {
+		candidates[i] = fmt.Sprintf("%s (%s)", names(m)[0], resourceID(m))
+	}
+	return "", fmt.Errorf("%q matches several %ss, use an ID: %s", ref, kind, strings.Join(candidates, ", "))
+}
+
+// resolveIDs resolves references to numeric IDs, fetching the candidates only
+// when a reference is not already a number.
+func resolveIDs(kind string, refs []string, fetch func() ([]interface{}, error), names func(map[string]interface{}) []string) ([]int, error) {
+	ids := make([]int, 0, len(refs))
+	var items []interface{}
+	for _, ref := range refs {
+		ref = strings.TrimSpace(ref)
+		if id, err := strconv.Atoi(ref); err == nil {
+			ids = append(ids, id)
+			continue
+		}
+		if items == nil {
+			var err error
+			if items, err = fetch(); err != nil {
+				return nil, err
+			}
+		}
+		id, err := resolveReference(kind, ref, items, names)
+		if err != nil {
+			return nil, err
+		}
+		n, err := strconv.Atoi(id)
+		if err != nil {
+			return nil, fmt.Errorf("%s %q has a non numeric ID: %s", kind, ref, id)
+		}
+		ids = append(ids, n)
+	}
+	return ids, nil
+}
+
+// resolveUsers resolves user IDs, names or email addresses.
+func resolveUsers(refs []string) ([]int, error) {
+	return resolveIDs("user", refs, func() ([]interface{}, error) {
+		return fetchAll("/users", map[string]string{"cloudTempleId": cloudTempleID})
+	}, func(user map[string]interface{}) []string {
+		names := []string{resourceName(user), stringField(user, "email")}
+		if first, last := stringField(user, "firstName"), stringField(user, "lastName"); first != "" || last != "" {
+			names = append(names, strings.TrimSpace(first+" "+last))
+		}
+		return names
+	})
+}
+
+// resolveCatalogItems resolves catalog item IDs, names or "Catalog > Item"
+// paths.
+func resolveCatalogItems(refs []string) ([]int, error) {
+	return resolveIDs("catalog item", refs, func() ([]interface{}, error) {
+		catalogs, err := fetchAll("/catalogs", map[string]string{
+			"cloudTempleId":  cloudTempleID,
+			"availableItems": "true",
+		})
+		if err != nil {
+			return nil, err
+		}
+		// Index the items with the path of their catalog
+		var items []interface{}
+		for _, c := range catalogs {
+			catalog, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for _, key := range []string{"items", "availableItems", "catalogItems"} {
+				list, _ := catalog[key].([]interface{})
+				for _, i := range list {
+					if item, ok := i.(map[string]interface{}); ok {
+						items = append(items, map[string]interface{}{
+							"id":   item["id"],
+							"name": resourceName(item),
+							"path": resourceName(catalog) + " > " + resourceName(item),
+						})
+					}
+				}
+			}
+		}
+		return items, nil
+	}, func(item map[string]interface{}) []string {
+		return []string{stringField(item, "name"), stringField(item, "path")}
+	})
+}
+
+// resolveTicketTags resolves ticket tag IDs or labels.
+func resolveTicketTags(refs []string) ([]int, error) {
+	return resolveIDs("ticket tag", refs, func() ([]interface{}, error) {
+		return fetchAll("/tickets/tags", map[string]string{"cloudTempleId": cloudTempleID})
+	}, func(tag map[string]interface{}) []string {
+		return []string{resourceName(tag)}
+	})
+}
+
+// resolveID resolves a single reference with one of the resolvers of this
+// file.
+func resolveID(resolve func([]string) ([]int, error), ref string) (int, error) {
+	ids, err := resolve([]string{ref})
+	if err != nil {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+// resolveHosts returns the hosts matching each reference, which can be a host
+// ID or a host name.
+func resolveHosts(refs []string) ([]map[string]interface{}, error) {
+	all, err := fetchAll("/hosts", map[string]string{"cloudTempleId": cloudTempleID})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching hosts: %w", err)
+	}
+	byID := make(map[string]map[string]interface{})
+	for _, h := range all {
+		if m, ok := h.(map[string]interface{}); ok {
+			byID[resourceID(m)] = m
+		}
+	}
+
+	hosts := make([]map[string]interface{}, 0, len(refs))
+	for _, ref := range refs {
+		id, err := resolveReference("host", strings.TrimSpace(ref), all, func(host map[string]interface{}) []string {
+			return []string{resourceName(host)}
+		})
+		if err != nil {
+			return nil, err
+		}
+		hosts = append(hosts, byID[id])
+	}
+	return hosts, nil
+}
+
+// resolveHostIDs resolves host IDs or names.
+func resolveHostIDs(refs []string) ([]int, error) {
+	return resolveIDs("host", refs, func() ([]interface{}, error) {
+		return fetchAll("/hosts", map[string]string{"cloudTempleId": cloudTempleID})
+	}, func(host map[string]interface{}) []string {
+		return []string{resourceName(host)}
+	})
+}
+
+// resolveHostTagIDs resolves host tag IDs or labels.
+func resolveHostTagIDs(refs []string) ([]int, error) {
+	return resolveIDs("host tag", refs, func() ([]interface{}, error) {
+		return fetchAll("/hosts/tags", map[string]string{"cloudTempleId": cloudTempleID})
+	}, func(tag map[string]interface{}) []string {
+		return []string{resourceName(tag)}
+	})
+}
+
+// resolveHostTags maps every label to its host tag ID, keyed by lowercase
+// label. Tags that do not exist yet are created when create is true and left
+// out of the result otherwise.
+func resolveHostTags(labels []string, create bool) (map[string]int, error) {
+	tagIDs := make(map[string]int)
+	if len(labels) == 0 {
+		return tagIDs, nil
+	}
+
+	tags, err := fetchAll("/hosts/tags", map[string]string{"cloudTempleId": cloudTempleID})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching host tags: %w", err)
+	}
+	for _, tag := range tags {
+		id, err := strconv.Atoi(resourceID(tag))
+		if err != nil {
+			continue
+		}
+		tagIDs[strings.ToLower(resourceName(tag))] = id
+	}
+
+	for _, label := range labels {
+		if _, ok := tagIDs[strings.ToLower(label)]; ok || !create {
+			continue
+		}
+		response, err := client.CreateHostTag(cloudTempleID, map[string]interface{}{"label": label})
+		if err != nil {
+			return nil, fmt.Errorf("error creating host tag %q: %w", label, err)
+		}
+		tag, err := decodeData(response)
+		if err != nil {
+			return nil, err
+		}
+		id, err := strconv.Atoi(resourceID(tag))
+		if err != nil {
+			return nil, fmt.Errorf("no ID returned for host tag %q", label)
+		}
+		tagIDs[strings.ToLower(label)] = id
+	}
+
+	return tagIDs, nil
+}
+
+// resolveTeams resolves team IDs or names.
+func resolveTeams(refs []string) ([]int, error) {
+	return resolveIDs("team", refs, func() ([]interface{}, error) {
+		return fetchAll("/teams", map[string]string{"cloudTempleId": cloudTempleID})
+	}, func(team map[string]interface{}) []string {
+		return []string{resourceName(team)}
+	})
+}
+
+// resolveAppliances resolves appliance IDs or names.
+func resolveAppliances(refs []string) ([]int, error) {
+	return resolveIDs("appliance", refs, func() ([]interface{}, error) {
+		return fetchAll("/appliances", map[string]string{"cloudTempleId": cloudTempleID})
+	}, func(appliance map[string]interface{}) []string {
+		return []string{resourceName(appliance)}
+	})
+}
+
+// resolveMonitoringServiceTemplates resolves monitoring service template IDs
+// or names.
+func resolveMonitoringServiceTemplates(refs []string) ([]int, error) {
+	return resolveIDs("monitoring service template", refs, func() ([]interface{}, error) {
+		return fetchAll("/monitoringServices/templates", map[string]string{})
+	}, func(template map[string]interface{}) []string {
+		return []string{resourceName(template)}
+	})
+}
@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/chrlesur/rtmscli/pkg/api"
 	"github.com/spf13/cobra"
@@ -16,11 +18,18 @@ var (
 	cloudTempleID string
 	host          string
 	client        *api.RTMSClient
+	clientHost    string
 	outputFormat  string
 	limit         int
 	batchSize     int
 	filter        string
 	debug         bool // new debug flag
+	assumeYes     bool
+	noCache       bool
+	refreshCache  bool
+	// commandContext is cancelled by the shell when Ctrl+C interrupts the
+	// running command
+	commandContext = context.Background()
 )
 
 var rootCmd = &cobra.Command{
@@ -29,44 +38,104 @@ var rootCmd = &cobra.Command{
 	Long: fmt.Sprintf(`RTMS CLI (version %s) allows you to interact with the RTMS API from the command line.
 It provides commands to manage appliances, hosts, tickets, and more.`, Version),
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// The status line is the only output of a plugin
+		if pluginMode(cmd) {
+			cmd.SilenceUsage, cmd.SilenceErrors = true, true
+		}
 		if cmd.Use == "version" {
 			return nil
 		}
 
-		validFormats := map[string]bool{"json": true, "text": true, "html": true, "markdown": true}
+		validFormats := map[string]bool{"json": true, "text": true, "html": true, "markdown": true, "table": true, "csv": true}
 		if !validFormats[outputFormat] {
-			return fmt.Errorf("invalid output format: %s. Supported formats are json, text, html, and markdown", outputFormat)
+			return fmt.Errorf("invalid output format: %s. Supported formats are json, text, html, markdown, table, and csv", outputFormat)
 		}
 
-		apiKey := os.Getenv("RTMS_API_KEY")
-		if apiKey == "" {
-			return fmt.Errorf("environment variable RTMS_API_KEY is not set")
+		// Shell completion creates the client only when it needs the API,
+		// and the cache commands work on local files
+		if cmd.Name() == cobra.ShellCompRequestCmd || cmd.Name() == cobra.ShellCompNoDescRequestCmd {
+			return nil
+		}
+		if cmd.HasParent() && (cmd.Parent().Name() == "completion" || cmd.Parent() == cacheCmd) {
+			return nil
 		}
 
+		return initClient()
+	},
+}
+
+// initClient creates the API client from the RTMS_API_KEY environment variable
+// and the global flags. The client is kept while the API host does not change,
+// so that the commands run from the shell share its connections.
+func initClient() error {
+	apiKey := os.Getenv("RTMS_API_KEY")
+	if apiKey == "" {
+		return fmt.Errorf("environment variable RTMS_API_KEY is not set")
+	}
+
+	if client == nil || clientHost != host {
 		var err error
 		client, err = api.NewRTMSClient(apiKey, host, IsBase64)
 		if err != nil {
 			return fmt.Errorf("error initializing RTMS client: %w", err)
 		}
+		clientHost = host
+	}
 
-		client.SetDebug(debug) // Pass the debug flag to api client
+	client.SetDebug(debug) // Pass the debug flag to api client
+	client.SetContext(commandContext)
 
+	if noCache {
+		client.DisableCache()
 		return nil
-	},
+	}
+	dir, err := cacheDir()
+	if err != nil {
+		return err
+	}
+	client.EnableCache(filepath.Join(dir, "http"), cloudTempleID, refreshCache)
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	for prefix, value := range cfg.Cache.TTL {
+		ttl, err := parseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid cache TTL for %s in config.json: %w", prefix, err)
+		}
+		client.SetCacheTTL(prefix, ttl)
+	}
+
+	return nil
+}
+
+// ExitError ends the program with an exit code once the command printed its
+// result, as the checks run in plugin mode do.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
 }
 
 func Execute() error {
-	return rootCmd.Execute()
+	executed, err := rootCmd.ExecuteC()
+	return pluginError(executed, err)
 }
 
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&cloudTempleID, "cloud-temple-id", "c", "", "Cloud Temple ID (required for most commands)")
 	rootCmd.PersistentFlags().StringVarP(&host, "host", "H", "rtms-api.cloud-temple.com", "RTMS API host")
-	rootCmd.PersistentFlags().StringVarP(&outputFormat, "format", "f", "json", "Output format (json, text, html, markdown)")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "format", "f", "json", "Output format (json, text, html, markdown, table, csv)")
 	rootCmd.PersistentFlags().IntVarP(&limit, "limit", "l", 0, "Limit the number of results returned (default: 0 for unlimited)")
 	rootCmd.PersistentFlags().IntVar(&batchSize, "batch-size", 100, "Number of items to fetch per batch")
 	rootCmd.PersistentFlags().StringVar(&filter, "filter", "", "Filter results (format depends on the command)")
 	rootCmd.PersistentFlags().BoolVarP(&debug, "debug", "d", false, "Enable debug mode")
+	rootCmd.PersistentFlags().BoolVarP(&assumeYes, "yes", "y", false, "Skip confirmation prompts for destructive operations")
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "Do not use or store cached API responses")
+	rootCmd.PersistentFlags().BoolVar(&refreshCache, "refresh", false, "Fetch cached API responses again and update the cache")
+	rootCmd.RegisterFlagCompletionFunc("format", completeFormats)
 
 	rootCmd.AddCommand(versionCmd)
 }
@@ -0,0 +1,552 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/peterh/liner"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const shellHistoryFile = "shell_history"
+
+// shellSession is the context kept between the commands typed in the shell.
+type shellSession struct {
+	tenant string
+	format string
+	// globals holds the other global flags given to the shell command, such
+	// as --host, applied to every command
+	globals map[string]string
+	// vars holds the variables usable as $name, such as the last host and
+	// ticket the commands were run on
+	vars map[string]string
+}
+
+// activeShell is the running shell session, if any.
+var activeShell *shellSession
+
+var shellBuiltins = []string{"context", "exit", "help", "quit", "set", "unset"}
+
+func init() {
+	// Interactive shell
+	shellCmd := &cobra.Command{
+		Use:   "shell",
+		Short: "Run rtmscli commands from an interactive prompt",
+		Long: `Run rtmscli commands from an interactive prompt.
+
+Commands are typed without the rtmscli prefix and share the same API client.
+The Cloud Temple ID and the output format given to the shell are kept for the
+whole session, and can be changed with "set tenant" and "set format". The last
+host and ticket a command was run on are available as $host and $ticket.
+
+The output of a command can be piped to local commands:
+
+  hosts list -f csv | grep DOWN
+
+Type "help" for the built-in commands.`,
+		Args: cobra.NoArgs,
+		RunE: runShell,
+	}
+	rootCmd.AddCommand(shellCmd)
+}
+
+func runShell(cmd *cobra.Command, args []string) error {
+	if activeShell != nil {
+		return fmt.Errorf("already running in the shell")
+	}
+	s := &shellSession{
+		tenant:  cloudTempleID,
+		format:  outputFormat,
+		globals: make(map[string]string),
+		vars:    make(map[string]string),
+	}
+	rootCmd.PersistentFlags().VisitAll(func(f *pflag.Flag) {
+		if f.Changed {
+			s.globals[f.Name] = f.Value.String()
+		}
+	})
+	activeShell = s
+	defer func() { activeShell = nil }()
+
+	line := liner.NewLiner()
+	defer line.Close()
+	line.SetCtrlCAborts(true)
+	line.SetTabCompletionStyle(liner.TabPrints)
+	line.SetWordCompleter(s.complete)
+
+	historyPath := ""
+	if dir, err := configDir(); err == nil {
+		historyPath = filepath.Join(dir, shellHistoryFile)
+		if f, err := os.Open(historyPath); err == nil {
+			line.ReadHistory(f)
+			f.Close()
+		}
+	}
+
+	// Errors are printed by the shell, and Ctrl+C interrupts the running
+	// command rather than the session
+	rootCmd.SilenceUsage, rootCmd.SilenceErrors = true, true
+	defer func() { rootCmd.SilenceUsage, rootCmd.SilenceErrors = false, false }()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	fmt.Println(`RTMS CLI shell. Type "help" for the built-in commands, "exit" or Ctrl+D to quit.`)
+	for {
+		input, err := line.Prompt(s.prompt())
+		if err == liner.ErrPromptAborted {
+			continue
+		}
+		if err == io.EOF {
+			fmt.Println()
+			break
+		}
+		if err != nil {
+			return err
+		}
+		input = strings.TrimSpace(input)
+		if input == "" {
+			continue
+		}
+		line.AppendHistory(input)
+		if historyPath != "" {
+			if err := saveShellHistory(line, historyPath); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+		}
+
+		quit, err := s.executeInterruptible(input, interrupt)
+		// The status of a plugin check is already printed
+		var exitErr *ExitError
+		if err != nil && !errors.As(err, &exitErr) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		if quit {
+			break
+		}
+	}
+	return nil
+}
+
+// executeInterruptible runs a line typed in the shell, cancelling the
+// requests of the command when Ctrl+C is pressed.
+func (s *shellSession) executeInterruptible(input string, interrupt chan os.Signal) (bool, error) {
+	// Drop a Ctrl+C pressed while no command was running
+	select {
+	case <-interrupt:
+	default:
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	commandContext = ctx
+	defer func() {
+		cancel()
+		commandContext = context.Background()
+		if client != nil {
+			client.SetContext(commandContext)
+		}
+	}()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-interrupt:
+			cancel()
+		case <-done:
+		}
+	}()
+
+	quit, err := s.execute(input)
+	if err != nil && ctx.Err() != nil {
+		err = fmt.Errorf("interrupted")
+	}
+	return quit, err
+}
+
+// saveShellHistory writes the history of the shell, so that it is kept even
+// when the shell does not end cleanly.
+func saveShellHistory(line *liner.State, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("error saving shell history: %w", err)
+	}
+	defer f.Close()
+	if _, err := line.WriteHistory(f); err != nil {
+		return fmt.Errorf("error saving shell history: %w", err)
+	}
+	return nil
+}
+
+func (s *shellSession) prompt() string {
+	if s.tenant == "" {
+		return "rtmscli> "
+	}
+	return fmt.Sprintf("rtmscli[%s]> ", s.tenant)
+}
+
+// lookup returns the value of a variable: a session variable, or else an
+// environment variable.
+func (s *shellSession) lookup(name string) (string, bool) {
+	switch name {
+	case "tenant":
+		return s.tenant, true
+	case "format":
+		return s.format, true
+	}
+	if value, ok := s.vars[name]; ok {
+		return value, true
+	}
+	return os.LookupEnv(name)
+}
+
+// execute runs a line typed in the shell and reports whether the session
+// should end.
+func (s *shellSession) execute(input string) (bool, error) {
+	words, pipe, err := parseShellLine(input, s.lookup)
+	if err != nil {
+		return false, err
+	}
+	if len(words) == 0 {
+		return false, fmt.Errorf("missing command before |")
+	}
+
+	switch words[0] {
+	case "exit", "quit":
+		return true, nil
+	case "help":
+		if len(words) == 1 {
+			printShellHelp()
+			return false, nil
+		}
+	case "context":
+		s.printContext()
+		return false, nil
+	case "set":
+		if len(words) != 3 {
+			return false, fmt.Errorf("usage: set <name> <value>")
+		}
+		return false, s.set(words[1], words[2])
+	case "unset":
+		if len(words) != 2 {
+			return false, fmt.Errorf("usage: unset <name>")
+		}
+		return false, s.set(words[1], "")
+	case "shell":
+		return false, fmt.Errorf("already running in the shell")
+	}
+
+	return false, s.run(words, pipe)
+}
+
+func (s *shellSession) set(name, value string) error {
+	switch name {
+	case "tenant":
+		s.tenant = value
+	case "format":
+		if value == "" {
+			value = "json"
+		}
+		for _, f := range outputFormats {
+			if f == value {
+				s.format = value
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid output format: %s. Supported formats are %s", value, strings.Join(outputFormats, ", "))
+	default:
+		if value == "" {
+			delete(s.vars, name)
+		} else {
+			s.vars[name] = value
+		}
+	}
+	return nil
+}
+
+func (s *shellSession) printContext() {
+	fmt.Printf("tenant  %s\n", s.tenant)
+	fmt.Printf("format  %s\n", s.format)
+	names := make([]string, 0, len(s.vars))
+	for name := range s.vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("$%-6s %s\n", name, s.vars[name])
+	}
+}
+
+func printShellHelp() {
+	fmt.Println(`Commands are the rtmscli commands without the rtmscli prefix, e.g.:
+
+  hosts list -f table
+  hosts details $host
+  tickets comments list $ticket | less
+
+Built-in commands:
+  context              Show the session context and variables
+  set tenant <id>      Change the Cloud Temple ID of the session
+  set format <format>  Change the output format of the session
+  set <name> <value>   Set the variable $name, e.g. set host 1234
+  unset <name>         Remove the variable $name
+  help <command>       Show the help of an rtmscli command
+  exit, quit           Leave the shell (or press Ctrl+D)
+
+Tab completes commands, flags and resource IDs. The output of a command can be
+piped to local commands with |.`)
+}
+
+// prepare resets the flags set by the previous command and applies the
+// session context to the global flags.
+func (s *shellSession) prepare() {
+	resetFlags(rootCmd)
+	for name, value := range s.globals {
+		rootCmd.PersistentFlags().Set(name, value)
+	}
+	cloudTempleID = s.tenant
+	outputFormat = s.format
+}
+
+// resetFlags sets the flags of a command tree back to their default values.
+func resetFlags(c *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			values := []string{}
+			if def := strings.Trim(f.DefValue, "[]"); def != "" {
+				values = strings.Split(def, ",")
+			}
+			slice.Replace(values)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	c.Flags().VisitAll(reset)
+	c.PersistentFlags().VisitAll(reset)
+	for _, sub := range c.Commands() {
+		resetFlags(sub)
+	}
+}
+
+// run executes an rtmscli command, writing its output to the standard input
+// of the pipe command when there is one.
+func (s *shellSession) run(words []string, pipe string) error {
+	s.prepare()
+	rootCmd.SetArgs(words)
+
+	if pipe == "" {
+		executed, err := rootCmd.ExecuteC()
+		if err == nil {
+			s.remember(executed)
+		}
+		return pluginError(executed, err)
+	}
+
+	var pipeCmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		pipeCmd = exec.Command("cmd", "/C", pipe)
+	} else {
+		pipeCmd = exec.Command("sh", "-c", pipe)
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	pipeCmd.Stdin = r
+	pipeCmd.Stdout = os.Stdout
+	pipeCmd.Stderr = os.Stderr
+	if err := pipeCmd.Start(); err != nil {
+		r.Close()
+		w.Close()
+		return fmt.Errorf("error running %s: %w", pipe, err)
+	}
+	r.Close()
+
+	stdout := os.Stdout
+	os.Stdout = w
+	executed, err := rootCmd.ExecuteC()
+	os.Stdout = stdout
+	w.Close()
+
+	if waitErr := pipeCmd.Wait(); waitErr != nil && err == nil {
+		if _, ok := waitErr.(*exec.ExitError); !ok {
+			err = waitErr
+		}
+	}
+	if err == nil {
+		s.remember(executed)
+	}
+	return pluginError(executed, err)
+}
+
+// remember keeps the host or ticket a command was run on as $host or
+// $ticket.
+func (s *shellSession) remember(executed *cobra.Command) {
+	args := executed.Flags().Args()
+	fields := strings.Fields(executed.Use)
+	if len(args) == 0 || len(fields) < 2 {
+		return
+	}
+	if _, err := strconv.Atoi(args[0]); err != nil {
+		return
+	}
+	switch placeholder := fields[1]; {
+	case placeholder == "[host-id]", placeholder == "[id]" && executed.Parent() == hostsCmd:
+		s.vars["host"] = args[0]
+	case placeholder == "[ticket-id]", placeholder == "[id]" && executed.Parent() == ticketsCmd:
+		s.vars["ticket"] = args[0]
+	}
+}
+
+// complete completes the word under the cursor with the completion of the
+// command tree, including the resource IDs offered to the shells.
+func (s *shellSession) complete(line string, pos int) (string, []string, string) {
+	head, tail := line[:pos], line[pos:]
+	if strings.Contains(head, "|") {
+		return head, nil, tail
+	}
+	start := strings.LastIndexAny(head, " \t") + 1
+	prefix, current := head[:start], head[start:]
+	words, _, err := parseShellLine(prefix, s.lookup)
+	if err != nil {
+		return head, nil, tail
+	}
+
+	s.prepare()
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(io.Discard)
+	rootCmd.SetArgs(append(append([]string{cobra.ShellCompNoDescRequestCmd}, words...), current))
+	rootCmd.Execute()
+	rootCmd.SetOut(nil)
+	rootCmd.SetErr(nil)
+
+	var candidates []string
+	directive := cobra.ShellCompDirectiveDefault
+	for _, l := range strings.Split(out.String(), "\n") {
+		if strings.HasPrefix(l, ":") {
+			if n, err := strconv.Atoi(l[1:]); err == nil {
+				directive = cobra.ShellCompDirective(n)
+			}
+			break
+		}
+		if l != "" {
+			candidates = append(candidates, l)
+		}
+	}
+	if len(words) == 0 {
+		for _, b := range shellBuiltins {
+			if strings.HasPrefix(b, current) {
+				candidates = append(candidates, b)
+			}
+		}
+		sort.Strings(candidates)
+	}
+
+	suffix := " "
+	if directive&cobra.ShellCompDirectiveNoSpace != 0 {
+		suffix = ""
+	}
+	completions := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		completions = append(completions, c+suffix)
+	}
+	return prefix, completions, tail
+}
+
+// parseShellLine splits a line into words as a shell would, honouring quotes
+// and backslashes and expanding $name and ${name} outside single quotes. The
+// text after the first unquoted | is returned as the pipe command.
+func parseShellLine(line string, lookup func(string) (string, bool)) ([]string, string, error) {
+	var words []string
+	var word strings.Builder
+	inWord, single, double := false, false, false
+	runes := []rune(line)
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\' && !single && i+1 < len(runes):
+			i++
+			word.WriteRune(runes[i])
+			inWord = true
+		case r == '\'' && !double:
+			single = !single
+			inWord = true
+		case r == '"' && !single:
+			double = !double
+			inWord = true
+		case r == '$' && !single:
+			name, end := shellVariableName(runes, i+1)
+			if name == "" {
+				word.WriteRune(r)
+				inWord = true
+				continue
+			}
+			value, ok := lookup(name)
+			if !ok {
+				return nil, "", fmt.Errorf("unknown variable $%s", name)
+			}
+			word.WriteString(value)
+			inWord = true
+			i = end - 1
+		case (r == ' ' || r == '\t') && !single && !double:
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case r == '|' && !single && !double:
+			if inWord {
+				words = append(words, word.String())
+			}
+			pipe := strings.TrimSpace(string(runes[i+1:]))
+			if pipe == "" {
+				return nil, "", fmt.Errorf("missing command after |")
+			}
+			return words, pipe, nil
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if single || double {
+		return nil, "", fmt.Errorf("unterminated quote")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, "", nil
+}
+
+// shellVariableName reads the name of a variable starting at index i, either
+// as name or as {name}, and returns it with the index following it.
+func shellVariableName(runes []rune, i int) (string, int) {
+	if i < len(runes) && runes[i] == '{' {
+		for j := i + 1; j < len(runes); j++ {
+			if runes[j] == '}' {
+				return string(runes[i+1 : j]), j + 1
+			}
+		}
+		return "", i
+	}
+	j := i
+	for j < len(runes) && (runes[j] == '_' || runes[j] >= 'a' && runes[j] <= 'z' || runes[j] >= 'A' && runes[j] <= 'Z' || runes[j] >= '0' && runes[j] <= '9') {
+		j++
+	}
+	return string(runes[i:j]), j
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestParseShellLine(t *testing.T) {
+	variables := map[string]string{"host": "1234", "name": "web 01", "empty": ""}
+	lookup := func(name string) (string, bool) {
+		value, ok := variables[name]
+		return value, ok
+	}
+	tests := []struct {
+		name      string
+		line      string
+		wantWords []string
+		wantPipe  string
+		wantErr   bool
+	}{
+		{name: "empty", line: "   ", wantWords: nil},
+		{name: "words", line: "hosts  list\t--limit 5", wantWords: []string{"hosts", "list", "--limit", "5"}},
+		{name: "double quotes", line: `tickets create --title "Disk full on web01"`, wantWords: []string{"tickets", "create", "--title", "Disk full on web01"}},
+		{name: "single quotes", line: `set note 'a "quoted" word'`, wantWords: []string{"set", "note", `a "quoted" word`}},
+		{name: "empty quotes", line: `set note ""`, wantWords: []string{"set", "note", ""}},
+		{name: "adjacent quotes", line: `--name=web"01 "'b'`, wantWords: []string{"--name=web01 b"}},
+		{name: "backslash", line: `a\ b c\"d "e\"f"`, wantWords: []string{"a b", `c"d`, `e"f`}},
+		{name: "variable", line: "hosts get $host", wantWords: []string{"hosts", "get", "1234"}},
+		{name: "braced variable", line: "--id=${host}0", wantWords: []string{"--id=12340"}},
+		{name: "variable with spaces stays one word", line: "hosts get $name", wantWords: []string{"hosts", "get", "web 01"}},
+		{name: "variable in double quotes", line: `echo "id $host"`, wantWords: []string{"echo", "id 1234"}},
+		{name: "no expansion in single quotes", line: `echo '$host'`, wantWords: []string{"echo", "$host"}},
+		{name: "escaped dollar", line: `echo \$host`, wantWords: []string{"echo", "$host"}},
+		{name: "empty variable is an empty word", line: "echo $empty x", wantWords: []string{"echo", "", "x"}},
+		{name: "lone dollar", line: "echo $ 5$", wantWords: []string{"echo", "$", "5$"}},
+		{name: "unknown variable", line: "hosts get $nope", wantErr: true},
+		{name: "pipe", line: "hosts list --format json | jq '.[] | .id'", wantWords: []string{"hosts", "list", "--format", "json"}, wantPipe: "jq '.[] | .id'"},
+		{name: "pipe without spaces", line: "hosts list|wc -l", wantWords: []string{"hosts", "list"}, wantPipe: "wc -l"},
+		{name: "quoted pipe", line: `tickets create --title "a | b"`, wantWords: []string{"tickets", "create", "--title", "a | b"}},
+		{name: "missing pipe command", line: "hosts list | ", wantErr: true},
+		{name: "unterminated double quote", line: `echo "abc`, wantErr: true},
+		{name: "unterminated single quote", line: "echo 'abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			words, pipe, err := parseShellLine(tt.line, lookup)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseShellLine(%q) = %q, %q, want an error", tt.line, words, pipe)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseShellLine(%q) returned %v", tt.line, err)
+			}
+			if !reflect.DeepEqual(words, tt.wantWords) || pipe != tt.wantPipe {
+				t.Errorf("parseShellLine(%q) = %q, %q, want %q, %q", tt.line, words, pipe, tt.wantWords, tt.wantPipe)
+			}
+		})
+	}
+}
+
+func TestResetFlags(t *testing.T) {
+	root := &cobra.Command{Use: "root"}
+	root.PersistentFlags().String("format", "text", "")
+	sub := &cobra.Command{Use: "sub"}
+	sub.Flags().Int("limit", 10, "")
+	sub.Flags().Bool("all", false, "")
+	sub.Flags().StringSlice("tags", []string{"a", "b"}, "")
+	sub.Flags().StringSlice("ids", nil, "")
+	root.AddCommand(sub)
+
+	for name, value := range map[string]string{"limit": "3", "all": "true", "tags": "x", "ids": "1,2"} {
+		if err := sub.Flags().Set(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := root.PersistentFlags().Set("format", "json"); err != nil {
+		t.Fatal(err)
+	}
+
+	resetFlags(root)
+
+	if format, _ := root.PersistentFlags().GetString("format"); format != "text" {
+		t.Errorf("format = %q, want %q", format, "text")
+	}
+	if limit, _ := sub.Flags().GetInt("limit"); limit != 10 {
+		t.Errorf("limit = %d, want 10", limit)
+	}
+	if all, _ := sub.Flags().GetBool("all"); all {
+		t.Errorf("all = true, want false")
+	}
+	if tags, _ := sub.Flags().GetStringSlice("tags"); !reflect.DeepEqual(tags, []string{"a", "b"}) {
+		t.Errorf("tags = %q, want %q", tags, []string{"a", "b"})
+	}
+	if ids, _ := sub.Flags().GetStringSlice("ids"); len(ids) != 0 {
+		t.Errorf("ids = %q, want none", ids)
+	}
+	for _, name := range []string{"limit", "all", "tags", "ids"} {
+		if sub.Flags().Changed(name) {
+			t.Errorf("flag %s is still marked as changed", name)
+		}
+	}
+	if root.PersistentFlags().Changed("format") {
+		t.Errorf("flag format is still marked as changed")
+	}
+}
@@ -0,0 +1,517 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var slaCmd = &cobra.Command{
+	Use:   "sla",
+	Short: "Compute service level reports",
+	Long:  `Compute availability reports of hosts and monitoring services from the state transitions recorded by the notifications.`,
+}
+
+func init() {
+	rootCmd.AddCommand(slaCmd)
+
+	// SLA report
+	slaReportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "Compute the availability of hosts and monitoring services over a period",
+		Long: `Compute the availability of hosts and monitoring services over a period, one
+row per calendar month, from the state transitions of the notifications.
+
+A monitoring service is unavailable while it is in one of the --down-states. A
+host is unavailable while any of its services is. The notification time period
+stops covering a host are excluded from the period.`,
+		RunE: slaReport,
+	}
+	slaReportCmd.Flags().String("from", "", "Start of the period: date, RFC3339 date or relative duration (default: start of the current month)")
+	slaReportCmd.Flags().String("to", "", "End of the period, a date alone includes the whole day (default: now)")
+	slaReportCmd.Flags().StringSlice("down-states", []string{"CRITICAL"}, "States counted as unavailable")
+	slaReportCmd.Flags().StringSlice("hosts", nil, "Host names or IDs to report on (default: all hosts)")
+	slaReportCmd.Flags().String("level", "all", "Rows to report: host, service or all")
+	slaReportCmd.RegisterFlagCompletionFunc("hosts", completeNames("/hosts"))
+	slaCmd.AddCommand(slaReportCmd)
+}
+
+type slaInterval struct {
+	start time.Time
+	end   time.Time
+}
+
+type slaTransition struct {
+	time  time.Time
+	state string
+}
+
+type slaService struct {
+	id          string
+	name        string
+	hostID      string
+	hostName    string
+	transitions []slaTransition
+}
+
+// nagiosStates maps the numeric Nagios states to their names.
+var nagiosStates = []string{"OK", "WARNING", "CRITICAL", "UNKNOWN"}
+
+func slaReport(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	fromFlag, _ := cmd.Flags().GetString("from")
+	toFlag, _ := cmd.Flags().GetString("to")
+	downStates, _ := cmd.Flags().GetStringSlice("down-states")
+	hostRefs, _ := cmd.Flags().GetStringSlice("hosts")
+	level, _ := cmd.Flags().GetString("level")
+
+	if level != "all" && level != "host" && level != "service" {
+		return fmt.Errorf("invalid level: %s. Valid levels are host, service and all", level)
+	}
+
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	to := now
+	var err error
+	if fromFlag != "" {
+		if from, err = parseTime(fromFlag, now); err != nil {
+			return fmt.Errorf("invalid start of period: %w", err)
+		}
+	}
+	if toFlag != "" {
+		if to, err = parseTime(toFlag, now); err != nil {
+			return fmt.Errorf("invalid end of period: %w", err)
+		}
+		if _, err := time.Parse("2006-01-02", toFlag); err == nil {
+			to = to.AddDate(0, 0, 1)
+		}
+	}
+	if to.After(now) {
+		to = now
+	}
+	if !from.Before(to) {
+		return fmt.Errorf("the start of the period must be before its end")
+	}
+
+	down := make(map[string]bool)
+	for _, s := range downStates {
+		down[strings.ToUpper(s)] = true
+	}
+
+	var hostFilter map[string]bool
+	if len(hostRefs) > 0 {
+		hosts, err := resolveHosts(hostRefs)
+		if err != nil {
+			return err
+		}
+		hostFilter = make(map[string]bool)
+		for _, h := range hosts {
+			hostFilter[resourceID(h)] = true
+		}
+	}
+
+	services, err := fetchSLAServices(from, to)
+	if err != nil {
+		return err
+	}
+	stops, err := fetchSLAStops()
+	if err != nil {
+		return err
+	}
+
+	// Group the services by host, sorted by name
+	byHost := make(map[string][]*slaService)
+	var hostIDs []string
+	for _, s := range services {
+		if hostFilter != nil && !hostFilter[s.hostID] {
+			continue
+		}
+		if _, ok := byHost[s.hostID]; !ok {
+			hostIDs = append(hostIDs, s.hostID)
+		}
+		byHost[s.hostID] = append(byHost[s.hostID], s)
+	}
+	sort.Slice(hostIDs, func(i, j int) bool {
+		return byHost[hostIDs[i]][0].hostName < byHost[hostIDs[j]][0].hostName
+	})
+
+	var rows []map[string]interface{}
+	for _, period := range slaPeriods(from, to) {
+		label := period.start.Format("2006-01")
+		for _, hostID := range hostIDs {
+			hostServices := byHost[hostID]
+			sort.Slice(hostServices, func(i, j int) bool { return hostServices[i].name < hostServices[j].name })
+
+			excluded := clipIntervals(mergeIntervals(append(append([]slaInterval(nil), stops[""]...), stops[hostID]...)), period)
+			monitored := period.end.Sub(period.start) - intervalsDuration(excluded)
+
+			var hostDown []slaInterval
+			hostIncidents := 0
+			var serviceRows []map[string]interface{}
+			for _, s := range hostServices {
+				durations := make(map[string]time.Duration)
+				incidents := 0
+				for _, segment := range s.segments(period) {
+					pieces := subtractIntervals(segment.slaInterval, excluded)
+					durations[segment.state] += intervalsDuration(pieces)
+					if down[segment.state] {
+						hostDown = append(hostDown, pieces...)
+						if segment.previous != "" && !down[segment.previous] && len(pieces) > 0 {
+							incidents++
+						}
+					}
+				}
+				var serviceDown time.Duration
+				for state, d := range durations {
+					if down[state] {
+						serviceDown += d
+					}
+				}
+				hostIncidents += incidents
+
+				row := slaRow(label, "service", s.hostName, s.name, monitored, serviceDown, period.end.Sub(period.start)-monitored, incidents)
+				for _, state := range nagiosStates {
+					row[strings.ToLower(state)] = formatSLADuration(durations[state])
+				}
+				serviceRows = append(serviceRows, row)
+			}
+
+			if level != "service" {
+				hostDownTime := intervalsDuration(mergeIntervals(hostDown))
+				rows = append(rows, slaRow(label, "host", hostServices[0].hostName, "", monitored, hostDownTime, period.end.Sub(period.start)-monitored, hostIncidents))
+			}
+			if level != "host" {
+				rows = append(rows, serviceRows...)
+			}
+		}
+	}
+
+	if len(rows) == 0 {
+		fmt.Println("No monitoring service found")
+		return nil
+	}
+	formattedOutput, err := formatOutput(rows, format)
+	if err != nil {
+		return err
+	}
+	fmt.Println(formattedOutput)
+	return nil
+}
+
+func slaRow(period, kind, host, service string, monitored, downtime, excluded time.Duration, incidents int) map[string]interface{} {
+	row := map[string]interface{}{
+		"period":    period,
+		"type":      kind,
+		"host":      host,
+		"downtime":  formatSLADuration(downtime),
+		"excluded":  formatSLADuration(excluded),
+		"incidents": incidents,
+		"service":   service,
+	}
+	// Host rows have the same columns as service rows, for tabular formats
+	for _, state := range nagiosStates {
+		row[strings.ToLower(state)] = ""
+	}
+	if monitored > 0 {
+		row["availability"] = math.Round((1-float64(downtime)/float64(monitored))*1e5) / 1e3
+	} else {
+		row["availability"] = nil
+	}
+	return row
+}
+
+func formatSLADuration(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
+
+// fetchSLAServices returns the monitoring services with their state
+// transitions up to the end of the period. The notifications are read from the
+// most recent one, back to the last one sent before the period for every
+// service, or to the first one. Services that were never notified before the
+// end of the period are in their current state.
+func fetchSLAServices(from, to time.Time) ([]*slaService, error) {
+	items, err := fetchAll("/monitoringServices", map[string]string{"cloudTempleId": cloudTempleID})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching monitoring services: %w", err)
+	}
+	var services []*slaService
+	byID := make(map[string]*slaService)
+	current := make(map[string]string)
+	add := func(resource map[string]interface{}) *slaService {
+		hostID, hostName := notificationHost(resource)
+		if hostName == "" {
+			hostName = hostID
+		}
+		s := &slaService{id: resourceID(resource), name: resourceName(resource), hostID: hostID, hostName: hostName}
+		if s.name == "" {
+			s.name = s.id
+		}
+		services = append(services, s)
+		byID[s.id] = s
+		return s
+	}
+	for _, item := range items {
+		if service, ok := item.(map[string]interface{}); ok {
+			s := add(service)
+			current[s.id] = notificationState(service)
+		}
+	}
+
+	// started holds the services whose state at the start of the period is
+	// known
+	started := make(map[string]bool)
+	allStarted := func() bool {
+		for _, s := range services {
+			if !started[s.id] {
+				return false
+			}
+		}
+		return true
+	}
+	for page := 1; ; page++ {
+		response, err := client.GetAllNotifications(cloudTempleID, map[string]string{
+			"order":        "DESC",
+			"orderBy":      "id",
+			"page":         strconv.Itoa(page),
+			"itemsPerPage": strconv.Itoa(batchSize),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error fetching notifications: %w", err)
+		}
+		data, err := decodeData(response)
+		if err != nil {
+			return nil, err
+		}
+		notifications, _ := data.([]interface{})
+
+		beforePeriod := false
+		for _, item := range notifications {
+			n, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			date := versionTime(n)
+			state := notificationState(n)
+			id, resource := notificationService(n)
+			if id == "" || date.IsZero() || state == "" || date.After(to) {
+				continue
+			}
+			if date.Before(from) {
+				beforePeriod = true
+				// Only the last state before the period matters
+				if started[id] {
+					continue
+				}
+				started[id] = true
+			}
+			s, ok := byID[id]
+			if !ok {
+				// The service may have been removed since
+				if resource == nil {
+					resource = map[string]interface{}{"id": id}
+				}
+				if _, ok := resource["host"]; !ok {
+					if host, ok := n["host"]; ok {
+						resource["host"] = host
+					}
+				}
+				s = add(resource)
+			}
+			s.transitions = append(s.transitions, slaTransition{time: date, state: state})
+		}
+		if len(notifications) < batchSize || (beforePeriod && allStarted()) {
+			break
+		}
+	}
+
+	// The services that were not notified until the end of the period have
+	// always been in their current state
+	for _, s := range services {
+		if len(s.transitions) == 0 && current[s.id] != "" {
+			s.transitions = []slaTransition{{time: from, state: current[s.id]}}
+		}
+	}
+	return services, nil
+}
+
+// notificationService returns the ID of the monitoring service a notification
+// was sent for, and the service itself when it is embedded.
+func notificationService(n map[string]interface{}) (string, map[string]interface{}) {
+	for _, key := range []string{"monitoringService", "service"} {
+		if service, ok := n[key].(map[string]interface{}); ok {
+			return resourceID(service), service
+		}
+	}
+	for _, key := range []string{"monitoringServiceId", "serviceId"} {
+		if id, ok := n[key]; ok && id != nil {
+			return resourceID(map[string]interface{}{"id": id}), nil
+		}
+	}
+	return "", nil
+}
+
+// notificationState returns the Nagios state of a notification, given by name
+// or as a Nagios return code.
+func notificationState(n map[string]interface{}) string {
+	switch state := rowState(n).(type) {
+	case string:
+		return strings.ToUpper(state)
+	case float64:
+		if state >= 0 && int(state) < len(nagiosStates) {
+			return nagiosStates[int(state)]
+		}
+	}
+	return ""
+}
+
+// fetchSLAStops returns the notification time period stops, keyed by host ID.
+// Stops that do not name any host apply to all of them and are keyed by "".
+func fetchSLAStops() (map[string][]slaInterval, error) {
+	items, err := fetchAll("/monitoringServices/notifications/timePeriodStops", map[string]string{"cloudTempleId": cloudTempleID})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching time period stops: %w", err)
+	}
+	stops := make(map[string][]slaInterval)
+	for _, item := range items {
+		stop, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		start := versionTime(map[string]interface{}{"date": stop["startDate"]})
+		end := versionTime(map[string]interface{}{"date": stop["endDate"]})
+		if start.IsZero() || end.IsZero() || !start.Before(end) {
+			continue
+		}
+		interval := slaInterval{start: start, end: end}
+		hosts, _ := stop["hosts"].([]interface{})
+		if len(hosts) == 0 {
+			stops[""] = append(stops[""], interval)
+		}
+		for _, host := range hosts {
+			id := resourceID(host)
+			if _, ok := host.(map[string]interface{}); !ok {
+				id = resourceID(map[string]interface{}{"id": host})
+			}
+			stops[id] = append(stops[id], interval)
+		}
+	}
+	return stops, nil
+}
+
+// slaSegment is a span of time during which a service stayed in a state.
+// previous is the state it left at the start of the span, if it changed during
+// the period.
+type slaSegment struct {
+	slaInterval
+	state    string
+	previous string
+}
+
+// segments splits the period by state. The state of a service is UNKNOWN until
+// its first notification.
+func (s *slaService) segments(period slaInterval) []slaSegment {
+	sort.SliceStable(s.transitions, func(i, j int) bool { return s.transitions[i].time.Before(s.transitions[j].time) })
+
+	current := slaSegment{slaInterval: slaInterval{start: period.start}, state: "UNKNOWN"}
+	var segments []slaSegment
+	for _, t := range s.transitions {
+		if !t.time.After(period.start) {
+			current.state = t.state
+			continue
+		}
+		if !t.time.Before(period.end) {
+			break
+		}
+		if t.state == current.state {
+			continue
+		}
+		current.end = t.time
+		segments = append(segments, current)
+		current = slaSegment{slaInterval: slaInterval{start: t.time}, state: t.state, previous: current.state}
+	}
+	current.end = period.end
+	return append(segments, current)
+}
+
+// slaPeriods splits an interval on calendar month boundaries.
+func slaPeriods(from, to time.Time) []slaInterval {
+	var periods []slaInterval
+	for start := from; start.Before(to); {
+		end := time.Date(start.Year(), start.Month()+1, 1, 0, 0, 0, 0, start.Location())
+		if end.After(to) {
+			end = to
+		}
+		periods = append(periods, slaInterval{start: start, end: end})
+		start = end
+	}
+	return periods
+}
+
+// mergeIntervals sorts intervals and merges the overlapping ones.
+func mergeIntervals(intervals []slaInterval) []slaInterval {
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].start.Before(intervals[j].start) })
+	var merged []slaInterval
+	for _, interval := range intervals {
+		if n := len(merged); n > 0 && !interval.start.After(merged[n-1].end) {
+			if interval.end.After(merged[n-1].end) {
+				merged[n-1].end = interval.end
+			}
+			continue
+		}
+		merged = append(merged, interval)
+	}
+	return merged
+}
+
+// clipIntervals restricts intervals to the period.
+func clipIntervals(intervals []slaInterval, period slaInterval) []slaInterval {
+	var clipped []slaInterval
+	for _, interval := range intervals {
+		if interval.start.Before(period.start) {
+			interval.start = period.start
+		}
+		if interval.end.After(period.end) {
+			interval.end = period.end
+		}
+		if interval.start.Before(interval.end) {
+			clipped = append(clipped, interval)
+		}
+	}
+	return clipped
+}
+
+// subtractIntervals returns the parts of interval outside of the sorted and
+// merged excluded intervals.
+func subtractIntervals(interval slaInterval, excluded []slaInterval) []slaInterval {
+	var pieces []slaInterval
+	start := interval.start
+	for _, e := range excluded {
+		if !e.end.After(start) {
+			continue
+		}
+		if !e.start.Before(interval.end) {
+			break
+		}
+		if e.start.After(start) {
+			pieces = append(pieces, slaInterval{start: start, end: e.start})
+		}
+		start = e.end
+	}
+	if start.Before(interval.end) {
+		pieces = append(pieces, slaInterval{start: start, end: interval.end})
+	}
+	return pieces
+}
+
+func intervalsDuration(intervals []slaInterval) time.Duration {
+	var total time.Duration
+	for _, interval := range intervals {
+		total += interval.end.Sub(interval.start)
+	}
+	return total
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// slaHour returns the time h hours after the start of 2026-09-01 UTC.
+func slaHour(h int) time.Time {
+	return time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(h) * time.Hour)
+}
+
+func slaSpan(start, end int) slaInterval {
+	return slaInterval{start: slaHour(start), end: slaHour(end)}
+}
+
+func TestSLASegments(t *testing.T) {
+	period := slaSpan(0, 10)
+	tests := []struct {
+		name        string
+		transitions []slaTransition
+		want        []slaSegment
+	}{
+		{
+			name: "no prior state",
+			transitions: []slaTransition{
+				{time: slaHour(4), state: "CRITICAL"},
+				{time: slaHour(6), state: "OK"},
+			},
+			want: []slaSegment{
+				{slaInterval: slaSpan(0, 4), state: "UNKNOWN"},
+				{slaInterval: slaSpan(4, 6), state: "CRITICAL", previous: "UNKNOWN"},
+				{slaInterval: slaSpan(6, 10), state: "OK", previous: "CRITICAL"},
+			},
+		},
+		{
+			name: "critical before the period",
+			transitions: []slaTransition{
+				{time: slaHour(-5), state: "CRITICAL"},
+				{time: slaHour(3), state: "OK"},
+			},
+			want: []slaSegment{
+				{slaInterval: slaSpan(0, 3), state: "CRITICAL"},
+				{slaInterval: slaSpan(3, 10), state: "OK", previous: "CRITICAL"},
+			},
+		},
+		{
+			name: "last state before the period wins",
+			transitions: []slaTransition{
+				{time: slaHour(-1), state: "WARNING"},
+				{time: slaHour(-8), state: "CRITICAL"},
+			},
+			want: []slaSegment{
+				{slaInterval: slaSpan(0, 10), state: "WARNING"},
+			},
+		},
+		{
+			name: "repeated state and transition after the period",
+			transitions: []slaTransition{
+				{time: slaHour(0), state: "OK"},
+				{time: slaHour(2), state: "OK"},
+				{time: slaHour(5), state: "CRITICAL"},
+				{time: slaHour(12), state: "OK"},
+			},
+			want: []slaSegment{
+				{slaInterval: slaSpan(0, 5), state: "OK"},
+				{slaInterval: slaSpan(5, 10), state: "CRITICAL", previous: "OK"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &slaService{transitions: tt.transitions}
+			if got := s.segments(period); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("segments() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeIntervals(t *testing.T) {
+	tests := []struct {
+		name      string
+		intervals []slaInterval
+		want      []slaInterval
+	}{
+		{"empty", nil, nil},
+		{"disjoint", []slaInterval{slaSpan(5, 6), slaSpan(0, 1)}, []slaInterval{slaSpan(0, 1), slaSpan(5, 6)}},
+		{"overlapping", []slaInterval{slaSpan(0, 4), slaSpan(2, 6)}, []slaInterval{slaSpan(0, 6)}},
+		{"touching", []slaInterval{slaSpan(0, 2), slaSpan(2, 3)}, []slaInterval{slaSpan(0, 3)}},
+		{"contained", []slaInterval{slaSpan(0, 10), slaSpan(2, 3), slaSpan(4, 5)}, []slaInterval{slaSpan(0, 10)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeIntervals(tt.intervals); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeIntervals() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClipIntervals(t *testing.T) {
+	got := clipIntervals([]slaInterval{slaSpan(-3, 1), slaSpan(2, 3), slaSpan(8, 12), slaSpan(11, 12)}, slaSpan(0, 10))
+	want := []slaInterval{slaSpan(0, 1), slaSpan(2, 3), slaSpan(8, 10)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("clipIntervals() = %v, want %v", got, want)
+	}
+}
+
+func TestSubtractIntervals(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval slaInterval
+		excluded []slaInterval
+		want     []slaInterval
+	}{
+		{"nothing excluded", slaSpan(0, 10), nil, []slaInterval{slaSpan(0, 10)}},
+		{"hole", slaSpan(0, 10), []slaInterval{slaSpan(2, 4)}, []slaInterval{slaSpan(0, 2), slaSpan(4, 10)}},
+		{"overlapping the start", slaSpan(0, 10), []slaInterval{slaSpan(-2, 3)}, []slaInterval{slaSpan(3, 10)}},
+		{"overlapping the end", slaSpan(0, 10), []slaInterval{slaSpan(8, 12)}, []slaInterval{slaSpan(0, 8)}},
+		{"all excluded", slaSpan(2, 4), []slaInterval{slaSpan(0, 10)}, nil},
+		{"outside", slaSpan(2, 4), []slaInterval{slaSpan(0, 1), slaSpan(5, 6)}, []slaInterval{slaSpan(2, 4)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := subtractIntervals(tt.interval, tt.excluded)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("subtractIntervals() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSLAPeriods(t *testing.T) {
+	from := time.Date(2026, 8, 15, 12, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 10, 3, 0, 0, 0, 0, time.UTC)
+	want := []slaInterval{
+		{start: from, end: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)},
+		{start: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC), end: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)},
+		{start: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), end: to},
+	}
+	if got := slaPeriods(from, to); !reflect.DeepEqual(got, want) {
+		t.Errorf("slaPeriods() = %v, want %v", got, want)
+	}
+	if got := intervalsDuration(want); got != to.Sub(from) {
+		t.Errorf("intervalsDuration() = %v, want %v", got, to.Sub(from))
+	}
+}
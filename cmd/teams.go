@@ -38,7 +38,7 @@ func init() {
 	createTeamCmd.Flags().String("name", "", "Team name")
 	createTeamCmd.Flags().String("information", "", "Team information")
 	createTeamCmd.Flags().StringSlice("contacts", nil, "Contact email addresses")
-	createTeamCmd.Flags().IntSlice("members", nil, "Team member identifiers")
+	createTeamCmd.Flags().StringSlice("members", nil, "Team members, as user IDs, names or email addresses")
 	createTeamCmd.MarkFlagRequired("name")
 	teamsCmd.AddCommand(createTeamCmd)
 
@@ -80,8 +80,8 @@ func init() {
 	editTeamCmd.Flags().Int("tenant", 0, "New tenant")
 	editTeamCmd.Flags().StringSlice("add-contacts", nil, "List of emails to add as team contacts")
 	editTeamCmd.Flags().StringSlice("remove-contacts", nil, "List of emails to delete from team contacts")
-	editTeamCmd.Flags().IntSlice("add-members", nil, "List of user identifiers to add as team members")
-	editTeamCmd.Flags().IntSlice("remove-members", nil, "List of user identifiers to remove from team members")
+	editTeamCmd.Flags().StringSlice("add-members", nil, "List of user IDs, names or email addresses to add as team members")
+	editTeamCmd.Flags().StringSlice("remove-members", nil, "List of user IDs, names or email addresses to remove from team members")
 	teamsCmd.AddCommand(editTeamCmd)
 }
 
@@ -89,7 +89,7 @@ func createTeam(cmd *cobra.Command, args []string) error {
 	name, _ := cmd.Flags().GetString("name")
 	information, _ := cmd.Flags().GetString("information")
 	contacts, _ := cmd.Flags().GetStringSlice("contacts")
-	members, _ := cmd.Flags().GetIntSlice("members")
+	memberRefs, _ := cmd.Flags().GetStringSlice("members")
 	format, _ := cmd.Flags().GetString("format")
 
 	teamData := map[string]interface{}{
@@ -101,7 +101,11 @@ func createTeam(cmd *cobra.Command, args []string) error {
 	if len(contacts) > 0 {
 		teamData["contacts"] = contacts
 	}
-	if len(members) > 0 {
+	if len(memberRefs) > 0 {
+		members, err := resolveUsers(memberRefs)
+		if err != nil {
+			return err
+		}
 		teamData["members"] = members
 	}
 
@@ -147,6 +151,14 @@ func getTeamDetails(cmd *cobra.Command, args []string) error {
 
 func removeTeam(cmd *cobra.Command, args []string) error {
 	format, _ := cmd.Flags().GetString("format")
+	confirmed, err := confirmDeletion("team", args[0], client.GetTeamDetails)
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		fmt.Println("Operation cancelled.")
+		return nil
+	}
 	response, err := client.RemoveTeam(args[0])
 	if err != nil {
 		return err
@@ -165,8 +177,8 @@ func editTeam(cmd *cobra.Command, args []string) error {
 	tenant, _ := cmd.Flags().GetInt("tenant")
 	addContacts, _ := cmd.Flags().GetStringSlice("add-contacts")
 	removeContacts, _ := cmd.Flags().GetStringSlice("remove-contacts")
-	addMembers, _ := cmd.Flags().GetIntSlice("add-members")
-	removeMembers, _ := cmd.Flags().GetIntSlice("remove-members")
+	addMembers, _ := cmd.Flags().GetStringSlice("add-members")
+	removeMembers, _ := cmd.Flags().GetStringSlice("remove-members")
 	format, _ := cmd.Flags().GetString("format")
 
 	teamData := make(map[string]interface{})
@@ -186,10 +198,18 @@ func editTeam(cmd *cobra.Command, args []string) error {
 		teamData["removeContacts"] = removeContacts
 	}
 	if len(addMembers) > 0 {
-		teamData["addMembers"] = addMembers
+		ids, err := resolveUsers(addMembers)
+		if err != nil {
+			return err
+		}
+		teamData["addMembers"] = ids
 	}
 	if len(removeMembers) > 0 {
-		teamData["removeMembers"] = removeMembers
+		ids, err := resolveUsers(removeMembers)
+		if err != nil {
+			return err
+		}
+		teamData["removeMembers"] = ids
 	}
 
 	response, err := client.EditTeam(args[0], teamData)
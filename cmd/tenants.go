@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"github.com/spf13/cobra"
@@ -59,8 +60,8 @@ func init() {
 	createTenantCmd.Flags().String("postal-code", "", "Tenant's postal code")
 	createTenantCmd.Flags().String("city", "", "Tenant's city")
 	createTenantCmd.Flags().String("country", "", "Tenant's country")
-	createTenantCmd.Flags().Int("responsible-team", 0, "Tenant's responsible team ID")
-	createTenantCmd.Flags().Int("contact", 0, "Tenant's contact ID")
+	createTenantCmd.Flags().String("responsible-team", "", "Tenant's responsible team, as an ID or a name")
+	createTenantCmd.Flags().String("contact", "", "Tenant's contact, as a user ID, name or email address")
 	createTenantCmd.Flags().StringSlice("watchers", nil, "List of default watcher email addresses")
 	createTenantCmd.Flags().Bool("is-enabled", true, "Is Tenant active?")
 	createTenantCmd.Flags().String("cloud-temple-id", "", "MySI Tenant's identifier")
@@ -134,9 +135,14 @@ func init() {
 	deleteSSHKeyCmd := &cobra.Command{
 		Use:   "delete [key-id]",
 		Short: "Delete an SSH key of a tenant",
-		Args:  cobra.ExactArgs(1),
-		RunE:  deleteTenantSSHKey,
-	}
+		Long: `Delete an SSH key of a tenant. The API has no endpoint returning a single
+key: with --tenant, the key is looked up among the keys of the tenant, so that
+it is shown before the confirmation and can be protected by its comment.
+Otherwise it can only be protected by ID.`,
+		Args: cobra.ExactArgs(1),
+		RunE: deleteTenantSSHKey,
+	}
+	deleteSSHKeyCmd.Flags().String("tenant", "", "ID of the tenant of the key")
 	sshKeysCmd.AddCommand(deleteSSHKeyCmd)
 
 	// Update SSH key
@@ -220,13 +226,22 @@ func createTenant(cmd *cobra.Command, args []string) error {
 	postalCode, _ := cmd.Flags().GetString("postal-code")
 	city, _ := cmd.Flags().GetString("city")
 	country, _ := cmd.Flags().GetString("country")
-	responsibleTeam, _ := cmd.Flags().GetInt("responsible-team")
-	contact, _ := cmd.Flags().GetInt("contact")
+	responsibleTeamRef, _ := cmd.Flags().GetString("responsible-team")
+	contactRef, _ := cmd.Flags().GetString("contact")
 	watchers, _ := cmd.Flags().GetStringSlice("watchers")
 	isEnabled, _ := cmd.Flags().GetBool("is-enabled")
 	cloudTempleID, _ := cmd.Flags().GetString("cloud-temple-id")
 	format, _ := cmd.Flags().GetString("format")
 
+	responsibleTeam, err := resolveID(resolveTeams, responsibleTeamRef)
+	if err != nil {
+		return err
+	}
+	contact, err := resolveID(resolveUsers, contactRef)
+	if err != nil {
+		return err
+	}
+
 	tenantData := map[string]interface{}{
 		"name":            name,
 		"phone":           phone,
@@ -287,6 +302,16 @@ func getTenantContacts(cmd *cobra.Command, args []string) error {
 func requestTenantDeletion(cmd *cobra.Command, args []string) error {
 	format, _ := cmd.Flags().GetString("format")
 	delete, _ := cmd.Flags().GetBool("delete")
+	if delete {
+		confirmed, err := confirmDeletion("tenant", args[0], client.GetTenantDetails)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			fmt.Println("Operation cancelled.")
+			return nil
+		}
+	}
 	response, err := client.RequestTenantDeletion(args[0], delete)
 	if err != nil {
 		return err
@@ -340,6 +365,21 @@ func generateTenantSSHKey(cmd *cobra.Command, args []string) error {
 
 func deleteTenantSSHKey(cmd *cobra.Command, args []string) error {
 	format, _ := cmd.Flags().GetString("format")
+	tenantID, _ := cmd.Flags().GetString("tenant")
+	var details func(string) ([]byte, error)
+	if tenantID != "" {
+		details = func(keyID string) ([]byte, error) {
+			return tenantSSHKey(tenantID, keyID)
+		}
+	}
+	confirmed, err := confirmDeletion("ssh-key", args[0], details)
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		fmt.Println("Operation cancelled.")
+		return nil
+	}
 	response, err := client.DeleteTenantSSHKey(args[0])
 	if err != nil {
 		return err
@@ -353,6 +393,25 @@ func deleteTenantSSHKey(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// tenantSSHKey returns an SSH key, looked up among the keys of its tenant.
+func tenantSSHKey(tenantID, keyID string) ([]byte, error) {
+	response, err := client.GetTenantSSHKeys(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := decodeData(response)
+	if err != nil {
+		return nil, err
+	}
+	keys, _ := data.([]interface{})
+	for _, key := range keys {
+		if resourceID(key) == keyID {
+			return json.Marshal(key)
+		}
+	}
+	return nil, fmt.Errorf("tenant %s has no SSH key %s", tenantID, keyID)
+}
+
 func updateTenantSSHKey(cmd *cobra.Command, args []string) error {
 	format, _ := cmd.Flags().GetString("format")
 	isActive, _ := cmd.Flags().GetBool("is-active")
@@ -6,8 +6,10 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
 )
 
 var ticketsCmd = &cobra.Command{
@@ -74,14 +76,23 @@ func init() {
 	createTicketCmd := &cobra.Command{
 		Use:   "create",
 		Short: "Create a new Ticket",
-		RunE:  createTicket,
+		Long: `Create a new Ticket.
+
+When --description is omitted in a terminal, $EDITOR is opened on a template
+with the name, owner and catalog items as front matter and the description
+below it.
+
+With --template, the ticket is created from templates/<name>.yaml in the
+configuration directory, rendered with the variables given with --var. Flags
+take precedence over the template.`,
+		RunE: createTicket,
 	}
 	createTicketCmd.Flags().String("name", "", "A title, the issue in short")
 	createTicketCmd.Flags().String("description", "", "Detailed description of the issue")
-	createTicketCmd.Flags().Int("owner", 0, "Identifier of the user in charge of solving the issue")
-	createTicketCmd.Flags().IntSlice("catalog-items", nil, "Collection of classification catalog item identifiers")
-	createTicketCmd.MarkFlagRequired("name")
-	createTicketCmd.MarkFlagRequired("description")
+	createTicketCmd.Flags().String("owner", "", "ID, name or email address of the user in charge of solving the issue")
+	createTicketCmd.Flags().StringSlice("catalog-items", nil, "Classification catalog items, as IDs, names or \"Catalog > Item\" paths")
+	createTicketCmd.Flags().String("template", "", "Name of a ticket template of the configuration directory, or path of a template file")
+	createTicketCmd.Flags().StringArray("var", nil, "Template variable as key=value (repeatable)")
 	ticketsCmd.AddCommand(createTicketCmd)
 
 	// Get tickets count
@@ -111,8 +122,8 @@ func init() {
 	}
 	editTicketCmd.Flags().String("name", "", "A new title, the issue in short")
 	editTicketCmd.Flags().String("description", "", "A new detailed description of the issue")
-	editTicketCmd.Flags().Int("owner", 0, "New identifier of the user in charge of solving the issue")
-	editTicketCmd.Flags().IntSlice("catalog-items", nil, "New collection of classification catalog item identifiers")
+	editTicketCmd.Flags().String("owner", "", "ID, name or email address of the new user in charge of solving the issue")
+	editTicketCmd.Flags().StringSlice("catalog-items", nil, "New classification catalog items, as IDs, names or \"Catalog > Item\" paths")
 	ticketsCmd.AddCommand(editTicketCmd)
 
 	// Get ticket catalogs
@@ -133,6 +144,7 @@ func init() {
 		Short: "Get tickets status stats",
 		RunE:  getTicketsStats,
 	}
+	addWatchFlag(getTicketsStatsCmd)
 	ticketsCmd.AddCommand(getTicketsStatsCmd)
 
 	// Attachments subcommand
@@ -208,13 +220,17 @@ func init() {
 	postCommentCmd := &cobra.Command{
 		Use:   "post [ticket-id]",
 		Short: "Post Ticket comment",
-		Args:  cobra.ExactArgs(1),
-		RunE:  postTicketComment,
+		Long: `Post Ticket comment.
+
+The content is taken from --content, from --content-file, or else composed in
+$EDITOR when running in a terminal.`,
+		Args: cobra.ExactArgs(1),
+		RunE: postTicketComment,
 	}
 	postCommentCmd.Flags().String("content", "", "Comment content")
+	postCommentCmd.Flags().String("content-file", "", "Read the comment content from a file, or from the standard input with -")
 	postCommentCmd.Flags().Bool("private", false, "Comment privacy")
 	postCommentCmd.Flags().Int("duration", 0, "Working time on the Ticket")
-	postCommentCmd.MarkFlagRequired("content")
 	commentsCmd.AddCommand(postCommentCmd)
 
 	// Edit comment
@@ -372,34 +388,105 @@ ProcessingComplete:
 func createTicket(cmd *cobra.Command, args []string) error {
 	name, _ := cmd.Flags().GetString("name")
 	description, _ := cmd.Flags().GetString("description")
-	owner, _ := cmd.Flags().GetInt("owner")
-	catalogItems, _ := cmd.Flags().GetIntSlice("catalog-items")
+	owner, _ := cmd.Flags().GetString("owner")
+	catalogItems, _ := cmd.Flags().GetStringSlice("catalog-items")
+	templateName, _ := cmd.Flags().GetString("template")
+	vars, _ := cmd.Flags().GetStringArray("var")
 	format, _ := cmd.Flags().GetString("format")
 
-	ticketData := map[string]interface{}{
-		"name":        name,
-		"description": description,
+	tmpl := &ticketTemplate{}
+	if templateName != "" {
+		var err error
+		if tmpl, err = loadTicketTemplate(templateName, vars); err != nil {
+			return err
+		}
+		if !cmd.Flags().Changed("name") {
+			name = tmpl.Name
+		}
+		if !cmd.Flags().Changed("description") {
+			description = tmpl.Description
+		}
+		if !cmd.Flags().Changed("owner") {
+			owner = tmpl.Owner
+		}
+		if !cmd.Flags().Changed("catalog-items") {
+			catalogItems = tmpl.CatalogItems
+		}
+	} else if len(vars) > 0 {
+		return fmt.Errorf("--var requires --template")
 	}
-	if owner != 0 {
-		ticketData["owner"] = owner
+
+	if description == "" {
+		if !isTerminal(os.Stdin) {
+			return fmt.Errorf("--name and --description are required when not running in a terminal")
+		}
+		frontMatter := yaml.MapSlice{
+			{Key: "name", Value: name},
+			{Key: "owner", Value: owner},
+			{Key: "catalog-items", Value: catalogItems},
+		}
+		values, body, err := composeInEditor("New ticket: the description goes below the front matter.\nLeave the description empty to abort.", frontMatter, "")
+		if err != nil {
+			return err
+		}
+		if body == "" {
+			return fmt.Errorf("aborted: empty description")
+		}
+		description = body
+		name = frontMatterString(values, "name")
+		owner = frontMatterString(values, "owner")
+		if catalogItems, err = frontMatterStrings(values, "catalog-items"); err != nil {
+			return err
+		}
 	}
-	if len(catalogItems) > 0 {
-		ticketData["catalogItemsCollection"] = catalogItems
+	tmpl.Name, tmpl.Description, tmpl.Owner, tmpl.CatalogItems = name, description, owner, catalogItems
+
+	_, response, err := createTicketFromTemplate(tmpl)
+	if response != nil {
+		// Utilisation de formatOutput pour formater la réponse
+		formattedOutput, err := formatOutput(response, format)
+		if err != nil {
+			return err
+		}
+
+		// Affichage de la réponse formatée
+		fmt.Println(formattedOutput)
 	}
+	return err
+}
 
-	response, err := client.CreateTicket(cloudTempleID, ticketData)
+// addTicketToTag adds a ticket to the tickets of a ticket tag.
+func addTicketToTag(tagID, ticketID string) error {
+	response, err := client.GetTicketTagDetails(tagID)
 	if err != nil {
 		return err
 	}
-	// Utilisation de formatOutput pour formater la réponse
-	formattedOutput, err := formatOutput(response, format)
+	data, err := decodeData(response)
 	if err != nil {
 		return err
 	}
+	tag, _ := data.(map[string]interface{})
+	current, _ := tag["tickets"].([]interface{})
 
-	// Affichage de la réponse formatée
-	fmt.Println(formattedOutput)
-	return nil
+	tickets := make([]int, 0, len(current)+1)
+	for _, t := range current {
+		id := resourceID(t)
+		if _, ok := t.(map[string]interface{}); !ok {
+			id = resourceID(map[string]interface{}{"id": t})
+		}
+		if id == ticketID {
+			return nil
+		}
+		if n, err := strconv.Atoi(id); err == nil {
+			tickets = append(tickets, n)
+		}
+	}
+	n, err := strconv.Atoi(ticketID)
+	if err != nil {
+		return fmt.Errorf("invalid ticket ID: %s", ticketID)
+	}
+	_, err = client.EditTicketTag(tagID, map[string]interface{}{"tickets": append(tickets, n)})
+	return err
 }
 
 func getTicketsCount(cmd *cobra.Command, args []string) error {
@@ -440,8 +527,8 @@ func getTicketDetails(cmd *cobra.Command, args []string) error {
 func editTicket(cmd *cobra.Command, args []string) error {
 	name, _ := cmd.Flags().GetString("name")
 	description, _ := cmd.Flags().GetString("description")
-	owner, _ := cmd.Flags().GetInt("owner")
-	catalogItems, _ := cmd.Flags().GetIntSlice("catalog-items")
+	owner, _ := cmd.Flags().GetString("owner")
+	catalogItems, _ := cmd.Flags().GetStringSlice("catalog-items")
 	format, _ := cmd.Flags().GetString("format")
 
 	ticketData := make(map[string]interface{})
@@ -451,11 +538,19 @@ func editTicket(cmd *cobra.Command, args []string) error {
 	if description != "" {
 		ticketData["description"] = description
 	}
-	if owner != 0 {
-		ticketData["owner"] = owner
+	if owner != "" {
+		id, err := resolveID(resolveUsers, owner)
+		if err != nil {
+			return err
+		}
+		ticketData["owner"] = id
 	}
 	if len(catalogItems) > 0 {
-		ticketData["catalogItemsCollection"] = catalogItems
+		ids, err := resolveCatalogItems(catalogItems)
+		if err != nil {
+			return err
+		}
+		ticketData["catalogItemsCollection"] = ids
 	}
 
 	response, err := client.EditTicket(args[0], ticketData)
@@ -507,6 +602,20 @@ func getTicketCatalogs(cmd *cobra.Command, args []string) error {
 
 func getTicketsStats(cmd *cobra.Command, args []string) error {
 	format, _ := cmd.Flags().GetString("format")
+	interval, err := watchInterval(cmd)
+	if err != nil {
+		return err
+	}
+	if interval > 0 {
+		return runWatch(cmd, interval, func() (interface{}, error) {
+			response, err := client.GetTicketsStats(cloudTempleID)
+			if err != nil {
+				return nil, err
+			}
+			return decodeData(response)
+		})
+	}
+
 	response, err := client.GetTicketsStats(cloudTempleID)
 	if err != nil {
 		return err
@@ -650,9 +759,42 @@ func postTicketComment(cmd *cobra.Command, args []string) error {
 	format, _ := cmd.Flags().GetString("format")
 	ticketID := args[0]
 	content, _ := cmd.Flags().GetString("content")
+	contentFile, _ := cmd.Flags().GetString("content-file")
 	private, _ := cmd.Flags().GetBool("private")
 	duration, _ := cmd.Flags().GetInt("duration")
 
+	var err error
+	switch {
+	case content != "" && contentFile != "":
+		return fmt.Errorf("--content and --content-file are mutually exclusive")
+	case contentFile != "":
+		if content, err = readContentFile(contentFile); err != nil {
+			return err
+		}
+	case content == "":
+		if !isTerminal(os.Stdin) {
+			return fmt.Errorf("--content or --content-file is required when not running in a terminal")
+		}
+		frontMatter := yaml.MapSlice{
+			{Key: "private", Value: private},
+			{Key: "duration", Value: duration},
+		}
+		values, body, err := composeInEditor(fmt.Sprintf("Comment on ticket %s: the content goes below the front matter.\nLeave the content empty to abort. Duration is the working time on the ticket.", ticketID), frontMatter, "")
+		if err != nil {
+			return err
+		}
+		content = body
+		if private, err = frontMatterBool(values, "private"); err != nil {
+			return err
+		}
+		if duration, err = frontMatterInt(values, "duration"); err != nil {
+			return err
+		}
+	}
+	if strings.TrimSpace(content) == "" {
+		return fmt.Errorf("aborted: empty comment")
+	}
+
 	commentData := map[string]interface{}{
 		"content": content,
 		"private": private,
@@ -781,6 +923,14 @@ func getTicketTagDetails(cmd *cobra.Command, args []string) error {
 
 func removeTicketTag(cmd *cobra.Command, args []string) error {
 	format, _ := cmd.Flags().GetString("format")
+	confirmed, err := confirmDeletion("ticket-tag", args[0], client.GetTicketTagDetails)
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		fmt.Println("Operation cancelled.")
+		return nil
+	}
 	response, err := client.RemoveTicketTag(args[0])
 	if err != nil {
 		return err
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	// Attach graphs to a ticket
+	attachGraphCmd := &cobra.Command{
+		Use:   "attach-graph [ticket-id]",
+		Short: "Attach the graphs of a monitoring service to a ticket",
+		Long: `Render the graphs of a monitoring service over a period, upload them as
+attachments of a ticket and post a comment summarising the minimum, maximum and
+average of every metric over the period.`,
+		Args: cobra.ExactArgs(1),
+		RunE: attachGraph,
+	}
+	attachGraphCmd.Flags().String("service", "", "Identifier of the monitoring service (required)")
+	attachGraphCmd.Flags().String("graph", "", "Label of the graph configuration to attach (default: all the graphs of the service)")
+	attachGraphCmd.Flags().String("since", "2h", "Duration of the period to render, up to now")
+	attachGraphCmd.Flags().String("image-format", "png", "Format of the images (png, svg)")
+	attachGraphCmd.Flags().Int("width", 900, "Width of the images in pixels")
+	attachGraphCmd.Flags().Int("height", 400, "Height of the images in pixels")
+	attachGraphCmd.Flags().Bool("private", false, "Post the summary as a private comment")
+	attachGraphCmd.MarkFlagRequired("service")
+	ticketsCmd.AddCommand(attachGraphCmd)
+}
+
+var attachmentNameEscaper = regexp.MustCompile(`[^a-z0-9]+`)
+
+func attachGraph(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	serviceID, _ := cmd.Flags().GetString("service")
+	graph, _ := cmd.Flags().GetString("graph")
+	sinceFlag, _ := cmd.Flags().GetString("since")
+	imageFormat, _ := cmd.Flags().GetString("image-format")
+	width, _ := cmd.Flags().GetInt("width")
+	height, _ := cmd.Flags().GetInt("height")
+	private, _ := cmd.Flags().GetBool("private")
+	ticketID := args[0]
+
+	since, err := parseDuration(sinceFlag)
+	if err != nil {
+		return err
+	}
+	end := time.Now()
+	start := end.Add(-since)
+
+	graphs, err := findGraphConfigurations(serviceID, graph)
+	if err != nil {
+		return err
+	}
+
+	// Render everything before uploading, so that nothing is attached when a
+	// graph cannot be rendered
+	type renderedGraph struct {
+		filename string
+		content  []byte
+		image    *graphImage
+	}
+	var rendered []renderedGraph
+	for _, g := range graphs {
+		image, err := newGraphImage(serviceID, g, start, end)
+		if err != nil {
+			return err
+		}
+		content, err := image.Encode(imageFormat, width, height)
+		if err != nil {
+			return err
+		}
+		name := strings.Trim(attachmentNameEscaper.ReplaceAllString(strings.ToLower(resourceName(g)), "-"), "-")
+		filename := fmt.Sprintf("service-%s-%s-%s.%s", serviceID, name, end.Format("20060102-1504"), imageFormat)
+		rendered = append(rendered, renderedGraph{filename: filename, content: content, image: image})
+	}
+
+	lines := []string{fmt.Sprintf("Metrics of monitoring service %s from %s to %s:", serviceID, start.Format("2006-01-02 15:04"), end.Format("2006-01-02 15:04"))}
+	for _, r := range rendered {
+		if _, err := client.UploadTicketAttachment(ticketID, r.filename, r.content); err != nil {
+			return fmt.Errorf("error uploading %s: %w", r.filename, err)
+		}
+		fmt.Printf("Graph %q attached to ticket %s as %s\n", r.image.Title, ticketID, r.filename)
+
+		for _, s := range r.image.Series {
+			lines = append(lines, fmt.Sprintf("- %s: %s", s.Metric, summarizeMetric(s.Points, s.Thresholds.Unit)))
+		}
+		lines = append(lines, fmt.Sprintf("  (graph attached as %s)", r.filename))
+	}
+
+	response, err := client.PostTicketComment(ticketID, map[string]interface{}{
+		"content": strings.Join(lines, "\n"),
+		"private": private,
+	})
+	if err != nil {
+		return fmt.Errorf("error posting the summary comment: %w", err)
+	}
+	formattedOutput, err := formatOutput(response, format)
+	if err != nil {
+		return err
+	}
+	fmt.Println(formattedOutput)
+	return nil
+}
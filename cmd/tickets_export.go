@@ -0,0 +1,472 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	// Export tickets
+	exportTicketCmd := &cobra.Command{
+		Use:   "export [ticket-id]",
+		Short: "Export the whole history of a ticket to a directory",
+		Long: `Export the details, catalog items, comments and attachments of a ticket to a
+directory: a chronological document, the downloaded attachments and a
+manifest.json listing every file with its SHA-256 checksum.
+
+With --tag, every ticket of the tag is exported to its own subdirectory.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: exportTickets,
+	}
+	exportTicketCmd.Flags().StringP("output", "o", "", "Output directory (required)")
+	exportTicketCmd.Flags().String("tag", "", "Export every ticket of this ticket tag instead of a single ticket")
+	exportTicketCmd.Flags().String("document-format", "markdown", "Format of the document (markdown, html)")
+	exportTicketCmd.Flags().Bool("skip-attachments", false, "Do not download the attachments")
+	exportTicketCmd.MarkFlagRequired("output")
+	ticketsCmd.AddCommand(exportTicketCmd)
+}
+
+// ticketEvent is an entry of the timeline of an exported ticket.
+type ticketEvent struct {
+	Time    time.Time
+	Title   string
+	Author  string
+	Body    string
+	Private bool
+	Link    string
+}
+
+type exportedFile struct {
+	Path         string `json:"path"`
+	Size         int    `json:"size"`
+	SHA256       string `json:"sha256"`
+	AttachmentID string `json:"attachmentId,omitempty"`
+}
+
+type ticketManifest struct {
+	TicketID     string         `json:"ticketId"`
+	Name         string         `json:"name"`
+	ExportedAt   time.Time      `json:"exportedAt"`
+	Document     string         `json:"document"`
+	Comments     int            `json:"comments"`
+	CatalogItems []string       `json:"catalogItems"`
+	Files        []exportedFile `json:"files"`
+	Details      interface{}    `json:"details"`
+}
+
+type tagManifest struct {
+	TagID      string             `json:"tagId"`
+	ExportedAt time.Time          `json:"exportedAt"`
+	Tickets    []tagManifestEntry `json:"tickets"`
+}
+
+type tagManifestEntry struct {
+	TicketID  string `json:"ticketId"`
+	Name      string `json:"name"`
+	Directory string `json:"directory"`
+}
+
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func exportTickets(cmd *cobra.Command, args []string) error {
+	output, _ := cmd.Flags().GetString("output")
+	tag, _ := cmd.Flags().GetString("tag")
+	documentFormat, _ := cmd.Flags().GetString("document-format")
+	skipAttachments, _ := cmd.Flags().GetBool("skip-attachments")
+
+	if documentFormat != "markdown" && documentFormat != "html" {
+		return fmt.Errorf("unsupported document format: %s. Supported formats are markdown and html", documentFormat)
+	}
+	if (len(args) == 0) == (tag == "") {
+		return fmt.Errorf("either a ticket ID or --tag is required")
+	}
+
+	if tag == "" {
+		manifest, err := exportTicket(args[0], output, documentFormat, skipAttachments)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Ticket %s exported to %s (%d comments, %d files)\n", manifest.TicketID, output, manifest.Comments, len(manifest.Files))
+		return nil
+	}
+
+	var tickets []interface{}
+	for page := 1; ; page++ {
+		response, err := client.GetTicketsByTag(tag, map[string]string{
+			"page":         strconv.Itoa(page),
+			"itemsPerPage": strconv.Itoa(batchSize),
+		})
+		if err != nil {
+			return fmt.Errorf("error fetching the tickets of tag %s: %w", tag, err)
+		}
+		data, err := decodeData(response)
+		if err != nil {
+			return err
+		}
+		list, _ := data.([]interface{})
+		tickets = append(tickets, list...)
+		if len(list) < batchSize {
+			break
+		}
+	}
+
+	manifest := tagManifest{TagID: tag, ExportedAt: time.Now()}
+	for _, ticket := range tickets {
+		id := resourceID(ticket)
+		if id == "" {
+			continue
+		}
+		directory := "ticket-" + id
+		ticketManifest, err := exportTicket(id, filepath.Join(output, directory), documentFormat, skipAttachments)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Ticket %s exported to %s (%d comments, %d files)\n", id, filepath.Join(output, directory), ticketManifest.Comments, len(ticketManifest.Files))
+		manifest.Tickets = append(manifest.Tickets, tagManifestEntry{TicketID: id, Name: ticketManifest.Name, Directory: directory})
+	}
+	if err := writeManifest(output, manifest); err != nil {
+		return err
+	}
+	fmt.Printf("%d tickets of tag %s exported to %s\n", len(manifest.Tickets), tag, output)
+	return nil
+}
+
+// exportTicket writes the document, attachments and manifest of a ticket to
+// dir.
+func exportTicket(id, dir, documentFormat string, skipAttachments bool) (*ticketManifest, error) {
+	response, err := client.GetTicketDetails(id)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching ticket %s: %w", id, err)
+	}
+	data, err := decodeData(response)
+	if err != nil {
+		return nil, err
+	}
+	details, _ := data.(map[string]interface{})
+	if details == nil {
+		return nil, fmt.Errorf("ticket %s not found", id)
+	}
+
+	comments, err := fetchTicketComments(id)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err = client.GetTicketCatalogs(id, map[string]string{"selectedItem": "true"})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching the catalogs of ticket %s: %w", id, err)
+	}
+	catalogs, err := decodeData(response)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err = client.ListTicketAttachments(id)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching the attachments of ticket %s: %w", id, err)
+	}
+	data, err = decodeData(response)
+	if err != nil {
+		return nil, err
+	}
+	attachments, _ := data.([]interface{})
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %v", err)
+	}
+	manifest := &ticketManifest{
+		TicketID:     id,
+		Name:         resourceName(details),
+		ExportedAt:   time.Now(),
+		Comments:     len(comments),
+		CatalogItems: catalogItemNames(catalogs, ""),
+		Details:      details,
+	}
+
+	created := ticketDate(details, "createdAt", "creationDate", "date")
+	events := []ticketEvent{{Time: created, Title: "Ticket created", Author: commentAuthor(details), Body: stringField(details, "description")}}
+	if resolved := ticketDate(details, "resolvedAt", "resolutionDate", "closedAt", "closeDate", "closedDate"); !resolved.IsZero() {
+		events = append(events, ticketEvent{Time: resolved, Title: "Ticket resolved"})
+	}
+
+	for _, item := range comments {
+		comment, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		title := "Comment"
+		if duration, ok := numberField(comment, "duration"); ok && duration > 0 {
+			title += fmt.Sprintf(" (%s logged)", formatMinutes(duration))
+		}
+		private, _ := comment["private"].(bool)
+		events = append(events, ticketEvent{
+			Time:    ticketDate(comment, "createdAt", "date"),
+			Title:   title,
+			Author:  commentAuthor(comment),
+			Body:    stringField(comment, "content"),
+			Private: private,
+		})
+	}
+
+	for _, item := range attachments {
+		attachment, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		attachmentID := resourceID(attachment)
+		name := stringField(attachment, "filename", "fileName", "originalName", "name")
+		if name == "" {
+			name = "attachment"
+		}
+		event := ticketEvent{
+			Time:   ticketDate(attachment, "createdAt", "date"),
+			Title:  "Attachment " + name,
+			Author: commentAuthor(attachment),
+		}
+		if !skipAttachments {
+			content, err := client.DownloadTicketAttachment(attachmentID)
+			if err != nil {
+				return nil, fmt.Errorf("error downloading attachment %s: %w", attachmentID, err)
+			}
+			path := filepath.Join("attachments", attachmentID+"-"+unsafeFilenameChars.ReplaceAllString(name, "_"))
+			file, err := writeExportFile(dir, path, content)
+			if err != nil {
+				return nil, err
+			}
+			file.AttachmentID = attachmentID
+			manifest.Files = append(manifest.Files, file)
+			event.Link = filepath.ToSlash(path)
+		}
+		events = append(events, event)
+	}
+
+	// Events without a date are kept at the end, in their original order
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].Time.IsZero() || events[j].Time.IsZero() {
+			return !events[i].Time.IsZero() && events[j].Time.IsZero()
+		}
+		return events[i].Time.Before(events[j].Time)
+	})
+
+	var document string
+	if documentFormat == "html" {
+		manifest.Document = "ticket.html"
+		document = renderTicketHTML(id, details, manifest.CatalogItems, events)
+	} else {
+		manifest.Document = "ticket.md"
+		document = renderTicketMarkdown(id, details, manifest.CatalogItems, events)
+	}
+	file, err := writeExportFile(dir, manifest.Document, []byte(document))
+	if err != nil {
+		return nil, err
+	}
+	manifest.Files = append(manifest.Files, file)
+
+	return manifest, writeManifest(dir, manifest)
+}
+
+// fetchTicketComments returns all the comments of a ticket, page by page.
+func fetchTicketComments(id string) ([]interface{}, error) {
+	var comments []interface{}
+	for page := 1; ; page++ {
+		response, err := client.GetTicketCommentsByTicket(id, map[string]string{
+			"page":         strconv.Itoa(page),
+			"itemsPerPage": strconv.Itoa(batchSize),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error fetching the comments of ticket %s: %w", id, err)
+		}
+		data, err := decodeData(response)
+		if err != nil {
+			return nil, err
+		}
+		list, _ := data.([]interface{})
+		comments = append(comments, list...)
+		if len(list) < batchSize {
+			return comments, nil
+		}
+	}
+}
+
+func writeExportFile(dir, path string, content []byte) (exportedFile, error) {
+	full := filepath.Join(dir, path)
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		return exportedFile{}, fmt.Errorf("failed to create directory: %v", err)
+	}
+	if err := ioutil.WriteFile(full, content, 0644); err != nil {
+		return exportedFile{}, fmt.Errorf("failed to write file: %v", err)
+	}
+	sum := sha256.Sum256(content)
+	return exportedFile{Path: filepath.ToSlash(path), Size: len(content), SHA256: hex.EncodeToString(sum[:])}, nil
+}
+
+func writeManifest(dir string, manifest interface{}) error {
+	var content bytes.Buffer
+	encoder := json.NewEncoder(&content)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "manifest.json"), content.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %v", err)
+	}
+	return nil
+}
+
+// catalogItemNames flattens the catalogs of a ticket into "Catalog > Item"
+// paths.
+func catalogItemNames(v interface{}, parent string) []string {
+	var names []string
+	switch value := v.(type) {
+	case []interface{}:
+		for _, item := range value {
+			names = append(names, catalogItemNames(item, parent)...)
+		}
+	case map[string]interface{}:
+		name := resourceName(value)
+		if parent != "" {
+			name = parent + " > " + name
+		}
+		var children []string
+		for _, key := range []string{"items", "catalogItems", "children"} {
+			children = append(children, catalogItemNames(value[key], name)...)
+		}
+		if len(children) == 0 {
+			return []string{name}
+		}
+		names = append(names, children...)
+	}
+	return names
+}
+
+// commentAuthor returns the name or email of the author of a comment,
+// attachment or ticket.
+func commentAuthor(resource map[string]interface{}) string {
+	for _, key := range []string{"user", "author", "createdBy", "creator"} {
+		switch author := resource[key].(type) {
+		case map[string]interface{}:
+			if name := resourceName(author); name != "" {
+				return name
+			}
+			if email, ok := author["email"].(string); ok {
+				return email
+			}
+		case string:
+			return author
+		}
+	}
+	return ""
+}
+
+func formatEventTime(t time.Time) string {
+	if t.IsZero() {
+		return "Unknown date"
+	}
+	return t.Format("2006-01-02 15:04:05 MST")
+}
+
+func ticketExportFields(details map[string]interface{}, catalogItems []string) [][2]string {
+	fields := [][2]string{{"Status", ticketStatusLabel(details)}}
+	owner := ticketOwnerName(details)
+	if owner == "" {
+		owner = "(unassigned)"
+	}
+	fields = append(fields, [2]string{"Owner", owner})
+	if created := ticketDate(details, "createdAt", "creationDate", "date"); !created.IsZero() {
+		fields = append(fields, [2]string{"Created", formatEventTime(created)})
+	}
+	if len(catalogItems) > 0 {
+		fields = append(fields, [2]string{"Catalog items", strings.Join(catalogItems, ", ")})
+	}
+	return fields
+}
+
+func renderTicketMarkdown(id string, details map[string]interface{}, catalogItems []string, events []ticketEvent) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("# Ticket %s: %s\n\n", id, resourceName(details)))
+	builder.WriteString("| Field | Value |\n|-------|-------|\n")
+	for _, field := range ticketExportFields(details, catalogItems) {
+		builder.WriteString(fmt.Sprintf("| %s | %s |\n", field[0], strings.Replace(field[1], "|", `\|`, -1)))
+	}
+	builder.WriteString("\n## Timeline\n\n")
+	for _, event := range events {
+		builder.WriteString(fmt.Sprintf("### %s - %s\n\n", formatEventTime(event.Time), event.Title))
+		if event.Author != "" {
+			builder.WriteString(fmt.Sprintf("*By %s*", event.Author))
+			if event.Private {
+				builder.WriteString(" *(private)*")
+			}
+			builder.WriteString("\n\n")
+		} else if event.Private {
+			builder.WriteString("*(private)*\n\n")
+		}
+		if event.Body != "" {
+			builder.WriteString(event.Body + "\n\n")
+		}
+		if event.Link != "" {
+			builder.WriteString(fmt.Sprintf("[%s](%s)\n\n", event.Link, event.Link))
+		}
+	}
+	return builder.String()
+}
+
+// renderTicketHTML renders a standalone page, with a print stylesheet so that
+// it can be printed to PDF from a browser.
+func renderTicketHTML(id string, details map[string]interface{}, catalogItems []string, events []ticketEvent) string {
+	var builder strings.Builder
+	title := html.EscapeString(fmt.Sprintf("Ticket %s: %s", id, resourceName(details)))
+	builder.WriteString("<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\"><title>" + title + "</title><style>")
+	builder.WriteString("body { font-family: Arial, sans-serif; max-width: 900px; margin: 20px auto; color: #222; }")
+	builder.WriteString("table { border-collapse: collapse; } th, td { padding: 6px 12px; text-align: left; border-bottom: 1px solid #ddd; }")
+	builder.WriteString(".event { border-left: 3px solid #4CAF50; padding: 4px 12px; margin: 16px 0; page-break-inside: avoid; }")
+	builder.WriteString(".event.private { border-left-color: #e6a700; } .meta { color: #666; font-size: 0.9em; } .body { white-space: pre-wrap; }")
+	builder.WriteString("@media print { body { margin: 0; max-width: none; } a { color: inherit; } }")
+	builder.WriteString("</style></head><body>\n")
+	builder.WriteString("<h1>" + title + "</h1>\n<table>\n")
+	for _, field := range ticketExportFields(details, catalogItems) {
+		builder.WriteString(fmt.Sprintf("<tr><th>%s</th><td>%s</td></tr>\n", html.EscapeString(field[0]), html.EscapeString(field[1])))
+	}
+	builder.WriteString("</table>\n<h2>Timeline</h2>\n")
+	for _, event := range events {
+		class := "event"
+		if event.Private {
+			class += " private"
+		}
+		builder.WriteString(fmt.Sprintf("<div class=\"%s\"><h3>%s</h3>\n", class, html.EscapeString(event.Title)))
+		meta := formatEventTime(event.Time)
+		if event.Author != "" {
+			meta += " by " + event.Author
+		}
+		if event.Private {
+			meta += " (private)"
+		}
+		builder.WriteString("<p class=\"meta\">" + html.EscapeString(meta) + "</p>\n")
+		if event.Body != "" {
+			builder.WriteString("<p class=\"body\">" + html.EscapeString(event.Body) + "</p>\n")
+		}
+		if event.Link != "" {
+			builder.WriteString(fmt.Sprintf("<p><a href=\"%s\">%s</a></p>\n", html.EscapeString(event.Link), html.EscapeString(event.Link)))
+		}
+		builder.WriteString("</div>\n")
+	}
+	builder.WriteString("</body></html>\n")
+	return builder.String()
+}
@@ -0,0 +1,313 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	// Ticket report
+	ticketsReportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "Compute ticket KPIs",
+		Long: `Compute ticket KPIs: counts per status, age of the open tickets, mean time to
+first response and to resolution, working time logged in comments per owner and
+per catalog item, and the unassigned and on delegation backlogs.
+
+The report has one row per value, with a category, an item and a value, so that
+it can be printed in any format. Use --format table or csv for spreadsheets.`,
+		RunE: ticketsReport,
+	}
+	ticketsReportCmd.Flags().String("since", "", "Only report on tickets created since then: date, RFC3339 date or relative duration (e.g. -7d)")
+	ticketsCmd.AddCommand(ticketsReportCmd)
+}
+
+// ticketAgeBuckets are the upper bounds of the age buckets of open tickets.
+var ticketAgeBuckets = []struct {
+	label string
+	max   time.Duration
+}{
+	{"< 1d", 24 * time.Hour},
+	{"1d - 7d", 7 * 24 * time.Hour},
+	{"7d - 30d", 30 * 24 * time.Hour},
+	{"> 30d", time.Duration(math.MaxInt64)},
+}
+
+func ticketsReport(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	sinceFlag, _ := cmd.Flags().GetString("since")
+
+	now := time.Now()
+	var since time.Time
+	if sinceFlag != "" {
+		var err error
+		if since, err = parseTime(sinceFlag, now); err != nil {
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+	}
+
+	tickets := make(map[string]map[string]interface{})
+	var ticketIDs []string
+	dataChan, errChan := client.StreamData("/tickets", map[string]string{"cloudTempleId": cloudTempleID}, batchSize)
+	for item := range dataChan {
+		ticket, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if created := ticketDate(ticket, "createdAt", "creationDate", "date"); !since.IsZero() && created.Before(since) {
+			continue
+		}
+		id := resourceID(ticket)
+		tickets[id] = ticket
+		ticketIDs = append(ticketIDs, id)
+	}
+	if err := <-errChan; err != nil {
+		return fmt.Errorf("error fetching tickets: %w", err)
+	}
+
+	// First comment and working time of every ticket
+	firstComment := make(map[string]time.Time)
+	minutes := make(map[string]float64)
+	err := forEachTicketComment(func(comment map[string]interface{}) {
+		ticketID := commentTicketID(comment)
+		if _, ok := tickets[ticketID]; !ok {
+			return
+		}
+		if date := ticketDate(comment, "createdAt", "date"); !date.IsZero() {
+			if first, ok := firstComment[ticketID]; !ok || date.Before(first) {
+				firstComment[ticketID] = date
+			}
+		}
+		if duration, ok := numberField(comment, "duration"); ok {
+			minutes[ticketID] += duration
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	statusCounts := make(map[string]int)
+	ageCounts := make(map[string]int)
+	ownerMinutes := make(map[string]float64)
+	catalogMinutes := make(map[string]float64)
+	var responseTimes, resolutionTimes []time.Duration
+	unassigned, onDelegation := 0, 0
+
+	for _, id := range ticketIDs {
+		ticket := tickets[id]
+		statusCounts[ticketStatusLabel(ticket)]++
+
+		created := ticketDate(ticket, "createdAt", "creationDate", "date")
+		resolved := ticketDate(ticket, "resolvedAt", "resolutionDate", "closedAt", "closeDate", "closedDate")
+		if first, ok := firstComment[id]; ok && !created.IsZero() && first.After(created) {
+			responseTimes = append(responseTimes, first.Sub(created))
+		}
+		if !resolved.IsZero() && !created.IsZero() {
+			resolutionTimes = append(resolutionTimes, resolved.Sub(created))
+		}
+
+		owner := ticketOwnerName(ticket)
+		if resolved.IsZero() {
+			if !created.IsZero() {
+				age := now.Sub(created)
+				for _, bucket := range ticketAgeBuckets {
+					if age < bucket.max {
+						ageCounts[bucket.label]++
+						break
+					}
+				}
+			}
+			if owner == "" {
+				unassigned++
+			}
+			if delegated, _ := ticket["isOnDelegation"].(bool); delegated {
+				onDelegation++
+			}
+		}
+
+		if owner == "" {
+			owner = "(unassigned)"
+		}
+		ownerMinutes[owner] += minutes[id]
+		for _, item := range ticketCatalogItems(ticket) {
+			catalogMinutes[item] += minutes[id]
+		}
+	}
+
+	var rows []map[string]interface{}
+	add := func(category, item string, value interface{}) {
+		rows = append(rows, map[string]interface{}{"category": category, "item": item, "value": value})
+	}
+
+	add("tickets", "total", len(ticketIDs))
+	for _, status := range sortedCountKeys(statusCounts) {
+		add("status", status, statusCounts[status])
+	}
+	for _, bucket := range ticketAgeBuckets {
+		add("open ticket age", bucket.label, ageCounts[bucket.label])
+	}
+	add("mean time", "to first response", formatMeanDuration(responseTimes))
+	add("mean time", "to resolution", formatMeanDuration(resolutionTimes))
+	for _, owner := range sortedMinuteKeys(ownerMinutes) {
+		add("working time per owner", owner, formatMinutes(ownerMinutes[owner]))
+	}
+	for _, item := range sortedMinuteKeys(catalogMinutes) {
+		add("working time per catalog item", item, formatMinutes(catalogMinutes[item]))
+	}
+	add("backlog", "unassigned", unassigned)
+	add("backlog", "on delegation", onDelegation)
+
+	formattedOutput, err := formatOutput(rows, format)
+	if err != nil {
+		return err
+	}
+	fmt.Println(formattedOutput)
+	return nil
+}
+
+// forEachTicketComment calls fn for every ticket comment of the tenant,
+// fetching them page by page.
+func forEachTicketComment(fn func(map[string]interface{})) error {
+	for page := 1; ; page++ {
+		response, err := client.GetTicketComments(cloudTempleID, map[string]string{
+			"page":         strconv.Itoa(page),
+			"itemsPerPage": strconv.Itoa(batchSize),
+		})
+		if err != nil {
+			return fmt.Errorf("error fetching ticket comments: %w", err)
+		}
+		data, err := decodeData(response)
+		if err != nil {
+			return err
+		}
+		comments, _ := data.([]interface{})
+		for _, item := range comments {
+			if comment, ok := item.(map[string]interface{}); ok {
+				fn(comment)
+			}
+		}
+		if len(comments) < batchSize {
+			return nil
+		}
+	}
+}
+
+func commentTicketID(comment map[string]interface{}) string {
+	if ticket, ok := comment["ticket"].(map[string]interface{}); ok {
+		return resourceID(ticket)
+	}
+	for _, key := range []string{"ticket", "ticketId"} {
+		if id, ok := comment[key]; ok && id != nil {
+			return resourceID(map[string]interface{}{"id": id})
+		}
+	}
+	return ""
+}
+
+// ticketDate returns the first date found in the keys, given as a timestamp or
+// as an RFC3339 date.
+func ticketDate(resource map[string]interface{}, keys ...string) time.Time {
+	for _, key := range keys {
+		if value, ok := resource[key]; ok && value != nil {
+			if date := versionTime(map[string]interface{}{"date": value}); !date.IsZero() {
+				return date
+			}
+		}
+	}
+	return time.Time{}
+}
+
+func ticketStatusLabel(ticket map[string]interface{}) string {
+	switch status := ticket["status"].(type) {
+	case map[string]interface{}:
+		return resourceName(status)
+	case nil:
+		return "(none)"
+	default:
+		return fmt.Sprintf("%v", status)
+	}
+}
+
+// ticketOwnerName returns the name of the owner of a ticket, or "" when it is
+// not assigned.
+func ticketOwnerName(ticket map[string]interface{}) string {
+	switch owner := ticket["owner"].(type) {
+	case map[string]interface{}:
+		if name := resourceName(owner); name != "" {
+			return name
+		}
+		if email, ok := owner["email"].(string); ok && email != "" {
+			return email
+		}
+		return resourceID(owner)
+	case string:
+		return owner
+	case float64:
+		if owner != 0 {
+			return strconv.FormatFloat(owner, 'f', -1, 64)
+		}
+	}
+	return ""
+}
+
+func ticketCatalogItems(ticket map[string]interface{}) []string {
+	items, _ := ticket["catalogItems"].([]interface{})
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		if m, ok := item.(map[string]interface{}); ok {
+			name := resourceName(m)
+			if name == "" {
+				name = resourceID(m)
+			}
+			names = append(names, name)
+			continue
+		}
+		names = append(names, fmt.Sprintf("%v", item))
+	}
+	return names
+}
+
+func formatMeanDuration(durations []time.Duration) string {
+	if len(durations) == 0 {
+		return "n/a"
+	}
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	return (total / time.Duration(len(durations))).Round(time.Minute).String()
+}
+
+// formatMinutes renders a working time logged in comments, in minutes.
+func formatMinutes(minutes float64) string {
+	return time.Duration(minutes * float64(time.Minute)).String()
+}
+
+func sortedCountKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedMinuteKeys sorts the keys by decreasing working time.
+func sortedMinuteKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if m[keys[i]] != m[keys[j]] {
+			return m[keys[i]] > m[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ticketTemplate describes a kind of ticket created repeatedly. Its text
+// fields are Go templates rendered with the variables given on the command
+// line.
+type ticketTemplate struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	// Owner is a user ID, name or email address
+	Owner string `yaml:"owner"`
+	// CatalogItems are catalog item IDs, names or "Catalog > Item" paths
+	CatalogItems []string `yaml:"catalog-items"`
+	// Tags are ticket tag IDs or labels the ticket is added to
+	Tags []string `yaml:"tags"`
+	// Comment is posted as a private comment once the ticket is created
+	Comment string `yaml:"comment"`
+	// Variables holds the default values of the variables
+	Variables map[string]string `yaml:"variables"`
+}
+
+var ticketTemplateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"now":   time.Now,
+}
+
+// loadTicketTemplate reads templates/<name>.yaml from the configuration
+// directory, or the file name itself when it is a path, and renders it with
+// the variables given as key=value.
+func loadTicketTemplate(name string, vars []string) (*ticketTemplate, error) {
+	path := name
+	if !strings.ContainsRune(name, os.PathSeparator) && filepath.Ext(name) == "" {
+		dir, err := configDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(dir, "templates", name+".yaml")
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			if _, err := os.Stat(filepath.Join(dir, "templates", name+".yml")); err == nil {
+				path = filepath.Join(dir, "templates", name+".yml")
+			}
+		}
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading ticket template %s: %w", name, err)
+	}
+	var t ticketTemplate
+	if err := yaml.UnmarshalStrict(content, &t); err != nil {
+		return nil, fmt.Errorf("invalid ticket template %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	for _, v := range vars {
+		i := strings.Index(v, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid variable %q, expected key=value", v)
+		}
+		values[v[:i]] = v[i+1:]
+	}
+	if err := renderTicketTemplate(&t, "ticket template "+name, values); err != nil {
+		var execErr template.ExecError
+		if errors.As(err, &execErr) {
+			return nil, fmt.Errorf("%w (use --var)", err)
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+// renderTicketTemplate renders the text fields of a ticket template with its
+// default variables overridden by values. The lists are replaced rather than
+// rendered in place, so that a template can be rendered several times.
+func renderTicketTemplate(t *ticketTemplate, source string, values map[string]string) error {
+	data := make(map[string]string)
+	for k, v := range t.Variables {
+		data[k] = v
+	}
+	for k, v := range values {
+		data[k] = v
+	}
+
+	render := func(field, text string) (string, error) {
+		tmpl, err := template.New(field).Funcs(ticketTemplateFuncs).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return "", fmt.Errorf("invalid %s in %s: %w", field, source, err)
+		}
+		var out bytes.Buffer
+		if err := tmpl.Execute(&out, data); err != nil {
+			return "", fmt.Errorf("error rendering the %s of %s: %w", field, source, err)
+		}
+		return strings.TrimSpace(out.String()), nil
+	}
+
+	fields := []struct {
+		name  string
+		value *string
+	}{
+		{"name", &t.Name},
+		{"description", &t.Description},
+		{"owner", &t.Owner},
+		{"comment", &t.Comment},
+	}
+	var err error
+	for _, f := range fields {
+		if *f.value, err = render(f.name, *f.value); err != nil {
+			return err
+		}
+	}
+	for _, list := range []*[]string{&t.CatalogItems, &t.Tags} {
+		rendered := make([]string, len(*list))
+		for i, item := range *list {
+			if rendered[i], err = render("list item", item); err != nil {
+				return err
+			}
+		}
+		if *list != nil {
+			*list = rendered
+		}
+	}
+	return nil
+}
+
+// createTicketFromTemplate creates the ticket described by a rendered
+// template, adds it to the tags of the template and posts its comment. It
+// returns the ID of the ticket and the response of its creation, which is set
+// as soon as the ticket is created, even when an error follows.
+func createTicketFromTemplate(t *ticketTemplate) (string, []byte, error) {
+	if t.Name == "" {
+		return "", nil, fmt.Errorf("the ticket name is required")
+	}
+	ticketData := map[string]interface{}{
+		"name":        t.Name,
+		"description": t.Description,
+	}
+	if t.Owner != "" {
+		id, err := resolveID(resolveUsers, t.Owner)
+		if err != nil {
+			return "", nil, err
+		}
+		ticketData["owner"] = id
+	}
+	if len(t.CatalogItems) > 0 {
+		ids, err := resolveCatalogItems(t.CatalogItems)
+		if err != nil {
+			return "", nil, err
+		}
+		ticketData["catalogItemsCollection"] = ids
+	}
+	tags, err := resolveTicketTags(t.Tags)
+	if err != nil {
+		return "", nil, err
+	}
+
+	response, err := client.CreateTicket(cloudTempleID, ticketData)
+	if err != nil {
+		return "", nil, err
+	}
+	data, err := decodeData(response)
+	if err != nil {
+		return "", response, err
+	}
+	ticketID := resourceID(data)
+	if ticketID == "" {
+		return "", response, fmt.Errorf("ticket created, but its ID is missing from the response")
+	}
+	for _, tag := range tags {
+		if err := addTicketToTag(strconv.Itoa(tag), ticketID); err != nil {
+			return ticketID, response, fmt.Errorf("ticket %s created, but adding it to tag %d failed: %w", ticketID, tag, err)
+		}
+	}
+	if t.Comment != "" {
+		if _, err := client.PostTicketComment(ticketID, map[string]interface{}{"content": t.Comment, "private": true}); err != nil {
+			return ticketID, response, fmt.Errorf("ticket %s created, but posting the comment of the template failed: %w", ticketID, err)
+		}
+	}
+	return ticketID, response, nil
+}
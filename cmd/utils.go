@@ -1,7 +1,9 @@
 package cmd
 
 import (
+	"bufio"
 	"encoding/base64"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"html"
@@ -10,6 +12,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -39,6 +42,10 @@ func formatOutput(data interface{}, format string) (string, error) {
 		return formatHTML(data)
 	case "markdown":
 		return formatMarkdown(data)
+	case "table":
+		return formatTable(data)
+	case "csv":
+		return formatCSV(data)
 	default:
 		return "", fmt.Errorf("unsupported format: %s", format)
 	}
@@ -208,6 +215,135 @@ func formatHTMLItem(item interface{}) string {
 	return builder.String()
 }
 
+// tabularData flattens data into a header and rows for the table and csv
+// formats. The data of an API response is used when present. Lists of objects
+// get one column per key, a single object one row per key.
+func tabularData(data interface{}) ([]string, [][]string) {
+	if m, ok := data.(map[string]interface{}); ok {
+		if inner, ok := m["data"]; ok {
+			data = inner
+		}
+	}
+
+	value := reflect.ValueOf(data)
+	switch value.Kind() {
+	case reflect.Slice:
+		columns := make(map[string]bool)
+		var header []string
+		for i := 0; i < value.Len(); i++ {
+			if item, ok := value.Index(i).Interface().(map[string]interface{}); ok {
+				for key := range item {
+					if !columns[key] {
+						columns[key] = true
+						header = append(header, key)
+					}
+				}
+			}
+		}
+		sort.Strings(header)
+		if len(header) == 0 {
+			header = []string{"value"}
+		}
+		rows := make([][]string, 0, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			item, ok := value.Index(i).Interface().(map[string]interface{})
+			if !ok {
+				rows = append(rows, []string{tabularCell(value.Index(i).Interface())})
+				continue
+			}
+			row := make([]string, len(header))
+			for j, key := range header {
+				row[j] = tabularCell(item[key])
+			}
+			rows = append(rows, row)
+		}
+		return header, rows
+	case reflect.Map:
+		if m, ok := data.(map[string]interface{}); ok {
+			var rows [][]string
+			for _, key := range getSortedKeys(m) {
+				rows = append(rows, []string{key, tabularCell(m[key])})
+			}
+			return []string{"key", "value"}, rows
+		}
+	}
+	return []string{"value"}, [][]string{{tabularCell(data)}}
+}
+
+// tabularCell renders a value in a single cell, nested values as compact JSON.
+func tabularCell(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case map[string]interface{}, []interface{}:
+		b, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Sprintf("%v", value)
+		}
+		return string(b)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func formatTable(data interface{}) (string, error) {
+	if data == nil {
+		return "No data available", nil
+	}
+	header, rows := tabularData(data)
+
+	widths := make([]int, len(header))
+	for i, h := range header {
+		widths[i] = len([]rune(h))
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			if i < len(widths) && len([]rune(cell)) > widths[i] {
+				widths[i] = len([]rune(cell))
+			}
+		}
+	}
+
+	var builder strings.Builder
+	writeRow := func(cells []string) {
+		line := make([]string, len(cells))
+		for i, cell := range cells {
+			line[i] = cell + strings.Repeat(" ", widths[i]-len([]rune(cell)))
+		}
+		builder.WriteString(strings.TrimRight(strings.Join(line, "  "), " ") + "\n")
+	}
+	upper := make([]string, len(header))
+	separator := make([]string, len(header))
+	for i, h := range header {
+		upper[i] = strings.ToUpper(h)
+		separator[i] = strings.Repeat("-", widths[i])
+	}
+	writeRow(upper)
+	writeRow(separator)
+	for _, row := range rows {
+		writeRow(row)
+	}
+	return strings.TrimSuffix(builder.String(), "\n"), nil
+}
+
+func formatCSV(data interface{}) (string, error) {
+	if data == nil {
+		return "", nil
+	}
+	header, rows := tabularData(data)
+
+	var builder strings.Builder
+	writer := csv.NewWriter(&builder)
+	if err := writer.Write(header); err != nil {
+		return "", err
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return "", fmt.Errorf("error converting to CSV: %w", err)
+	}
+	return strings.TrimSuffix(builder.String(), "\n"), nil
+}
+
 func getSortedKeys(m map[string]interface{}) []string {
 	keys := make([]string, 0, len(m))
 	for k := range m {
@@ -281,35 +417,19 @@ func updateListCommand(cmd *cobra.Command, endpoint string, paramsFunc func() ma
 			params["filter"] = filter
 		}
 
-		// Use StreamData to fetch data
-		dataChan, errChan := client.StreamData(endpoint, params, batchSize)
-
-		count := 0
-		var data []interface{}
-
-		// Channel to signal when data collection is done
-		done := make(chan struct{})
-
-		// Goroutine to collect data
-		go func() {
-			defer close(done)
-			for item := range dataChan {
-				data = append(data, item)
-				count++
-				if limit > 0 && count >= limit {
-					return
-				}
-			}
-		}()
+		interval, err := watchInterval(cmd)
+		if err != nil {
+			return err
+		}
+		if interval > 0 {
+			return runWatch(cmd, interval, func() (interface{}, error) {
+				return fetchList(endpoint, params)
+			})
+		}
 
-		// Wait for data collection or error
-		select {
-		case <-done:
-			// Data collection finished naturally
-		case err := <-errChan:
-			if err != nil {
-				return fmt.Errorf("error fetching data: %w", err)
-			}
+		data, err := fetchList(endpoint, params)
+		if err != nil {
+			return err
 		}
 
 		// Check if any data was fetched
@@ -334,6 +454,33 @@ func updateListCommand(cmd *cobra.Command, endpoint string, paramsFunc func() ma
 	cmd.Flags().IntVar(&limit, "limit", 0, "Limit the number of results returned")
 	cmd.Flags().IntVar(&batchSize, "batch-size", 100, "Number of items to fetch per batch")
 	cmd.Flags().StringVar(&filter, "filter", "", "Filter results (format depends on the command)")
+	addWatchFlag(cmd)
+}
+
+// fetchList fetches the items of a list endpoint, up to the --limit flag. No
+// page is requested once the limit is reached.
+func fetchList(endpoint string, params map[string]string) ([]interface{}, error) {
+	stop := make(chan struct{})
+	dataChan, errChan := client.StreamDataUntil(endpoint, params, batchSize, stop)
+
+	var data []interface{}
+	for item := range dataChan {
+		data = append(data, item)
+		if limit > 0 && len(data) >= limit {
+			close(stop)
+			// Wait for the stream to stop, the items sent meanwhile are
+			// dropped
+			for range dataChan {
+			}
+			return data, nil
+		}
+	}
+
+	// The error channel is closed before the data channel
+	if err := <-errChan; err != nil {
+		return nil, fmt.Errorf("error fetching data: %w", err)
+	}
+	return data, nil
 }
 
 func intSliceToString(slice []int) string {
@@ -348,3 +495,207 @@ func IsBase64(s string) bool {
 	_, err := base64.StdEncoding.DecodeString(s)
 	return err == nil
 }
+
+// isTerminal reports whether f is attached to an interactive terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	if info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	// The null device is a character device too
+	if null, err := os.Stat(os.DevNull); err == nil && os.SameFile(info, null) {
+		return false
+	}
+	return true
+}
+
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// promptLine writes prompt to stderr and returns the line typed by the user,
+// without its trailing newline.
+func promptLine(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	line, err := stdinReader.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// decodeData unmarshals an API response and unwraps its "data" envelope when
+// there is one.
+func decodeData(response []byte) (interface{}, error) {
+	var decoded interface{}
+	if err := json.Unmarshal(response, &decoded); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+	if m, ok := decoded.(map[string]interface{}); ok {
+		if data, ok := m["data"]; ok {
+			return data, nil
+		}
+	}
+	return decoded, nil
+}
+
+// resourceName returns the most descriptive name field of a decoded resource.
+func resourceName(resource interface{}) string {
+	m, ok := resource.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	for _, key := range []string{"name", "label", "email", "comment"} {
+		if v, ok := m[key].(string); ok && v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// confirmDeletion shows the resource about to be deleted and asks the user to
+// confirm. Protected resources are always refused, and a non-interactive
+// session must pass --yes. details may be nil when the resource has no details
+// endpoint.
+func confirmDeletion(kind, id string, details func(string) ([]byte, error)) (bool, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return false, err
+	}
+
+	var response []byte
+	var name string
+	if details != nil {
+		response, err = details(id)
+		if err != nil {
+			return false, fmt.Errorf("error fetching %s %s: %w", kind, id, err)
+		}
+		if resource, err := decodeData(response); err == nil {
+			name = resourceName(resource)
+		}
+	}
+
+	if cfg.isProtected(kind, id, name) {
+		return false, fmt.Errorf("%s %s is protected and cannot be deleted from the CLI", kind, id)
+	}
+
+	if assumeYes {
+		return true, nil
+	}
+
+	if !isTerminal(os.Stdin) {
+		return false, fmt.Errorf("refusing to delete %s %s without confirmation in a non-interactive session, use --yes", kind, id)
+	}
+
+	if response != nil {
+		formattedOutput, err := formatOutput(response, "text")
+		if err != nil {
+			return false, err
+		}
+		fmt.Fprintln(os.Stderr, formattedOutput)
+	}
+
+	label := id
+	if name != "" {
+		label = fmt.Sprintf("%s (%s)", id, name)
+	}
+	answer, err := promptLine(fmt.Sprintf("Delete %s %s? [y/N]: ", kind, label))
+	if err != nil {
+		return false, err
+	}
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes", nil
+}
+
+// fetchAll collects every item of a paginated endpoint.
+func fetchAll(endpoint string, params map[string]string) ([]interface{}, error) {
+	dataChan, errChan := client.StreamData(endpoint, params, batchSize)
+
+	var items []interface{}
+	for item := range dataChan {
+		items = append(items, item)
+	}
+
+	if err := <-errChan; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// resourceID returns the "id" field of a decoded resource as a string.
+func resourceID(resource interface{}) string {
+	m, ok := resource.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	switch id := m["id"].(type) {
+	case float64:
+		return strconv.FormatFloat(id, 'f', -1, 64)
+	case string:
+		return id
+	}
+	return ""
+}
+
+// parseDuration extends time.ParseDuration with the "d" (day) and "w" (week)
+// units, e.g. "2h", "1d" or "1w2d".
+func parseDuration(s string) (time.Duration, error) {
+	var total time.Duration
+	rest := strings.TrimSpace(s)
+	negative := strings.HasPrefix(rest, "-")
+	rest = strings.TrimPrefix(rest, "-")
+	for _, unit := range []struct {
+		suffix string
+		value  time.Duration
+	}{{"w", 7 * 24 * time.Hour}, {"d", 24 * time.Hour}} {
+		if i := strings.Index(rest, unit.suffix); i > 0 {
+			n, err := strconv.Atoi(rest[:i])
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			total += time.Duration(n) * unit.value
+			rest = rest[i+1:]
+		}
+	}
+	if rest != "" {
+		d, err := time.ParseDuration(rest)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		total += d
+	}
+	if negative {
+		total = -total
+	}
+	return total, nil
+}
+
+// parseTime parses an absolute date (RFC3339, YYYY-MM-DD or a Unix timestamp
+// in seconds or milliseconds) or a date relative to now, such as -24h or -7d.
+func parseTime(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "now" {
+		return now, nil
+	}
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		d, err := parseDuration(strings.TrimPrefix(s, "+"))
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q", s)
+		}
+		return now.Add(d), nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if len(s) >= 13 {
+			return time.Unix(0, n*int64(time.Millisecond)), nil
+		}
+		return time.Unix(n, 0), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q, use RFC3339, YYYY-MM-DD, a Unix timestamp or a relative duration such as -24h", s)
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "30s", want: 30 * time.Second},
+		{in: "2h", want: 2 * time.Hour},
+		{in: "1d", want: 24 * time.Hour},
+		{in: "1w", want: 7 * 24 * time.Hour},
+		{in: "1w2d", want: 9 * 24 * time.Hour},
+		{in: "1d12h30m", want: 36*time.Hour + 30*time.Minute},
+		{in: "-7d", want: -7 * 24 * time.Hour},
+		{in: " 1h ", want: time.Hour},
+		{in: "", want: 0},
+		{in: "1.5d", wantErr: true},
+		{in: "3days", wantErr: true},
+		{in: "soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseDuration(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseDuration(%q) = %v, want an error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseDuration(%q) returned %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Errorf("parseDuration(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{in: "now", want: now},
+		{in: "-24h", want: now.Add(-24 * time.Hour)},
+		{in: "-7d", want: now.AddDate(0, 0, -7)},
+		{in: "-1w2d", want: now.AddDate(0, 0, -9)},
+		{in: "+2h", want: now.Add(2 * time.Hour)},
+		{in: "1792400000", want: time.Unix(1792400000, 0)},
+		{in: "1792400000123", want: time.Unix(1792400000, 123*int64(time.Millisecond))},
+		{in: "2026-09-01T08:30:00Z", want: time.Date(2026, 9, 1, 8, 30, 0, 0, time.UTC)},
+		{in: "2026-09-01T08:30:00+02:00", want: time.Date(2026, 9, 1, 6, 30, 0, 0, time.UTC)},
+		{in: "2026-09-01", want: time.Date(2026, 9, 1, 0, 0, 0, 0, time.Local)},
+		{in: "-soon", wantErr: true},
+		{in: "01/09/2026", wantErr: true},
+		{in: "yesterday", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseTime(tt.in, now)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseTime(%q) = %v, want an error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseTime(%q) returned %v", tt.in, err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseTime(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
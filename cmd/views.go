@@ -21,6 +21,12 @@ func init() {
 		Short: "List hosts, services or templates in a monitoring view",
 		Args:  cobra.ExactArgs(2),
 		RunE:  listViewItems,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) == 0 {
+				return []string{"host", "service", "template"}, cobra.ShellCompDirectiveNoFileComp
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
 	}
 	listViewItemsCmd.Flags().String("order", "DESC", "Data sort order (ASC, DESC)")
 	listViewItemsCmd.Flags().String("order-by", "id", "Attribute of the element on which to order")
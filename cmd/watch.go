@@ -0,0 +1,296 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	ansiReset       = "\033[0m"
+	ansiRed         = "\033[31m"
+	ansiGreen       = "\033[32m"
+	ansiYellow      = "\033[33m"
+	ansiClearScreen = "\033[H\033[2J"
+)
+
+// watchEvent describes a change between two refreshes of a watched command.
+type watchEvent struct {
+	Time     string      `json:"time"`
+	Event    string      `json:"event"`
+	ID       string      `json:"id"`
+	Previous interface{} `json:"previous,omitempty"`
+	State    interface{} `json:"state,omitempty"`
+	Item     interface{} `json:"item,omitempty"`
+	Error    string      `json:"error,omitempty"`
+}
+
+// addWatchFlag registers the --watch and --interval flags on cmd. Used
+// without a value, --watch refreshes the command every 10 seconds; the interval
+// is given either as --watch=30s or as --interval 30s, which implies --watch.
+// Commands without positional arguments reject them, so that "--watch 30s" is
+// not silently refreshed every 10 seconds.
+func addWatchFlag(cmd *cobra.Command) {
+	cmd.Flags().String("watch", "", "Refresh the output at the given interval, given as --watch=30s (default 10s)")
+	cmd.Flags().Lookup("watch").NoOptDefVal = "10s"
+	cmd.Flags().String("interval", "", "Refresh interval, e.g. --interval 30s (implies --watch)")
+	if cmd.Args == nil {
+		cmd.Args = func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return nil
+			}
+			if _, err := parseDuration(args[0]); err == nil {
+				return fmt.Errorf("unexpected argument %q, use --watch=%s or --interval %s to set the refresh interval", args[0], args[0], args[0])
+			}
+			return fmt.Errorf("unexpected argument %q", args[0])
+		}
+	}
+}
+
+// watchInterval returns the refresh interval requested with --watch or
+// --interval, or zero when the command is not watched.
+func watchInterval(cmd *cobra.Command) (time.Duration, error) {
+	if cmd.Flags().Lookup("watch") == nil {
+		return 0, nil
+	}
+	value, _ := cmd.Flags().GetString("watch")
+	if cmd.Flags().Changed("interval") {
+		value, _ = cmd.Flags().GetString("interval")
+	}
+	if value == "" {
+		return 0, nil
+	}
+	interval, err := parseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if interval < time.Second {
+		return 0, fmt.Errorf("watch interval must be at least 1s")
+	}
+	return interval, nil
+}
+
+// runWatch polls fetch every interval until interrupted. On a terminal the
+// output is redrawn in place with the rows that changed state highlighted;
+// otherwise only the changes are written, as one JSON event per line.
+func runWatch(cmd *cobra.Command, interval time.Duration, fetch func() (interface{}, error)) error {
+	format, _ := cmd.Flags().GetString("format")
+	interactive := isTerminal(os.Stdout)
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var previous map[string]interface{}
+	for {
+		data, err := fetch()
+		now := time.Now()
+		switch {
+		case err != nil && interactive:
+			fmt.Printf("%s%s: %v%s\n", ansiRed, now.Format("15:04:05"), err, ansiReset)
+		case err != nil:
+			printWatchEvents([]watchEvent{{Time: now.Format(time.RFC3339), Event: "error", Error: err.Error()}})
+		default:
+			current := watchSnapshot(data)
+			events := diffWatchSnapshots(previous, current, now)
+			if interactive {
+				if err := renderWatch(cmd, interval, format, data, events, previous == nil, now); err != nil {
+					return err
+				}
+			} else {
+				printWatchEvents(events)
+			}
+			previous = current
+		}
+
+		select {
+		case <-interrupt:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// watchSnapshot indexes the fetched data by row ID. Lists are keyed by the id
+// of their items, single objects such as stats by field name.
+func watchSnapshot(data interface{}) map[string]interface{} {
+	snapshot := make(map[string]interface{})
+	switch v := data.(type) {
+	case []interface{}:
+		for i, item := range v {
+			key := resourceID(item)
+			if key == "" {
+				key = fmt.Sprintf("#%d", i+1)
+			}
+			snapshot[key] = item
+		}
+	case map[string]interface{}:
+		for key, value := range v {
+			snapshot[key] = value
+		}
+	default:
+		snapshot["value"] = data
+	}
+	return snapshot
+}
+
+// rowState returns the part of a row whose change is reported: the status or
+// state of a resource, or the value itself for scalar rows.
+func rowState(row interface{}) interface{} {
+	if m, ok := row.(map[string]interface{}); ok {
+		for _, key := range []string{"status", "state"} {
+			if state, ok := m[key]; ok {
+				return state
+			}
+		}
+		return nil
+	}
+	return row
+}
+
+func diffWatchSnapshots(previous, current map[string]interface{}, now time.Time) []watchEvent {
+	timestamp := now.Format(time.RFC3339)
+	var events []watchEvent
+
+	keys := make([]string, 0, len(current))
+	for key := range current {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		row := current[key]
+		old, existed := previous[key]
+		switch {
+		case !existed:
+			events = append(events, watchEvent{Time: timestamp, Event: "added", ID: key, State: rowState(row), Item: row})
+		case !reflect.DeepEqual(rowState(old), rowState(row)):
+			events = append(events, watchEvent{Time: timestamp, Event: "changed", ID: key, Previous: rowState(old), State: rowState(row), Item: row})
+		}
+	}
+
+	keys = keys[:0]
+	for key := range previous {
+		if _, ok := current[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		events = append(events, watchEvent{Time: timestamp, Event: "removed", ID: key, Previous: rowState(previous[key]), Item: previous[key]})
+	}
+
+	return events
+}
+
+func printWatchEvents(events []watchEvent) {
+	encoder := json.NewEncoder(os.Stdout)
+	for _, event := range events {
+		encoder.Encode(event)
+	}
+}
+
+// renderWatch clears the terminal and prints the data, highlighting the rows
+// added or changed since the previous refresh and listing the removed ones.
+// Every row is new on the first refresh, so nothing is highlighted then.
+func renderWatch(cmd *cobra.Command, interval time.Duration, format string, data interface{}, events []watchEvent, firstRefresh bool, now time.Time) error {
+	highlighted := make(map[string]string)
+	if !firstRefresh {
+		for _, event := range events {
+			highlighted[event.ID] = event.Event
+		}
+	}
+
+	var builder strings.Builder
+	builder.WriteString(ansiClearScreen)
+	builder.WriteString(fmt.Sprintf("Every %s: %s    %s\n\n", interval, cmd.CommandPath(), now.Format("2006-01-02 15:04:05")))
+
+	if items, ok := data.([]interface{}); ok {
+		if len(items) == 0 {
+			builder.WriteString("No data found.\n")
+		}
+		for i, item := range items {
+			formattedOutput, err := formatOutput(item, format)
+			if err != nil {
+				return err
+			}
+			key := resourceID(item)
+			if key == "" {
+				key = fmt.Sprintf("#%d", i+1)
+			}
+			builder.WriteString(highlight(formattedOutput, highlighted[key], rowState(item)))
+			builder.WriteString("\n")
+		}
+	} else {
+		formattedOutput, err := formatOutput(data, format)
+		if err != nil {
+			return err
+		}
+		builder.WriteString(formattedOutput)
+		builder.WriteString("\n")
+	}
+
+	if !firstRefresh && len(events) > 0 {
+		builder.WriteString("\nChanges since last refresh:\n")
+		for _, event := range events {
+			line := fmt.Sprintf("  %-8s %s", event.Event, event.ID)
+			switch event.Event {
+			case "added":
+				line += fmt.Sprintf(" (%v)", event.State)
+			case "changed":
+				line += fmt.Sprintf(" (%v -> %v)", event.Previous, event.State)
+			case "removed":
+				line += fmt.Sprintf(" (was %v)", event.Previous)
+			}
+			builder.WriteString(highlight(line, event.Event, event.State) + "\n")
+		}
+	}
+
+	fmt.Print(builder.String())
+	return nil
+}
+
+// highlight colors text according to the change of its row: green for rows
+// back to a healthy state or removed, red for rows in a failed state, yellow
+// for any other change.
+func highlight(text, event string, state interface{}) string {
+	if event == "" {
+		return text
+	}
+	color := ansiYellow
+	switch {
+	case event == "removed":
+		color = ansiGreen
+	case isHealthyState(state):
+		color = ansiGreen
+	case isFailedState(state):
+		color = ansiRed
+	}
+	return color + text + ansiReset
+}
+
+func isHealthyState(state interface{}) bool {
+	s, ok := state.(string)
+	return ok && (strings.EqualFold(s, "UP") || strings.EqualFold(s, "OK"))
+}
+
+func isFailedState(state interface{}) bool {
+	s, ok := state.(string)
+	if !ok {
+		return false
+	}
+	switch strings.ToUpper(s) {
+	case "DOWN", "UNREACHABLE", "CRITICAL", "UNKNOWN":
+		return true
+	}
+	return false
+}
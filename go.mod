@@ -1,8 +1,16 @@
 module github.com/chrlesur/rtmscli
 
-go 1.16
+go 1.18
 
 require (
+	github.com/fogleman/gg v1.3.0
+	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
+	github.com/mattn/go-runewidth v0.0.9
+	github.com/nsf/termbox-go v1.1.1
+	github.com/peterh/liner v1.2.2
 	github.com/russross/blackfriday/v2 v2.0.1
 	github.com/spf13/cobra v1.2.1
+	github.com/spf13/pflag v1.0.5
+	golang.org/x/image v0.18.0 // indirect
+	gopkg.in/yaml.v2 v2.4.0
 )
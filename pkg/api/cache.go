@@ -0,0 +1,195 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultCacheTTLs are the endpoints whose GET responses are cached, with
+// how long a response is used without asking the API again. An endpoint
+// matches the longest prefix it starts with. Responses of other endpoints are
+// never cached.
+var DefaultCacheTTLs = map[string]time.Duration{
+	"/catalogs":                     time.Hour,
+	"/monitoringServices/templates": time.Hour,
+	"/nagiosCommands":               time.Hour,
+	"/teams":                        10 * time.Minute,
+	"/users":                        10 * time.Minute,
+}
+
+// cacheInvalidations lists the endpoints that change the cached data of
+// another endpoint.
+var cacheInvalidations = map[string]string{
+	"/nagiosPlugins": "/nagiosCommands",
+}
+
+// CacheEntry is a cached response, stored as one JSON file per request.
+type CacheEntry struct {
+	Method       string    `json:"method"`
+	URL          string    `json:"url"`
+	Endpoint     string    `json:"endpoint"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	StoredAt     time.Time `json:"storedAt"`
+	Expires      time.Time `json:"expires"`
+	Body         []byte    `json:"body"`
+}
+
+type responseCache struct {
+	dir           string
+	cloudTempleID string
+	refresh       bool
+	ttls          map[string]time.Duration
+}
+
+// EnableCache stores the responses of the endpoints of DefaultCacheTTLs in
+// dir. Entries are keyed by the method, the URL, the Cloud Temple ID and the
+// API key. With refresh, cached responses are replaced without being used.
+func (c *RTMSClient) EnableCache(dir, cloudTempleID string, refresh bool) {
+	ttls := make(map[string]time.Duration, len(DefaultCacheTTLs))
+	for prefix, ttl := range DefaultCacheTTLs {
+		ttls[prefix] = ttl
+	}
+	c.cache = &responseCache{
+		dir:           dir,
+		cloudTempleID: cloudTempleID,
+		refresh:       refresh,
+		ttls:          ttls,
+	}
+}
+
+// DisableCache stops reading and writing cached responses.
+func (c *RTMSClient) DisableCache() {
+	c.cache = nil
+}
+
+// SetCacheTTL sets how long the responses of the endpoints starting with
+// prefix are cached. A zero TTL disables caching for them.
+func (c *RTMSClient) SetCacheTTL(prefix string, ttl time.Duration) {
+	if c.cache != nil {
+		c.cache.ttls[prefix] = ttl
+	}
+}
+
+// ttl returns the TTL of the longest prefix matching the endpoint.
+func (rc *responseCache) ttl(endpoint string) time.Duration {
+	best, ttl := "", time.Duration(0)
+	for prefix, d := range rc.ttls {
+		if cacheMatches(endpoint, prefix) && len(prefix) > len(best) {
+			best, ttl = prefix, d
+		}
+	}
+	return ttl
+}
+
+func (rc *responseCache) path(apiKey, method, rawURL string) string {
+	sum := sha256.Sum256([]byte(method + "\n" + rawURL + "\n" + rc.cloudTempleID + "\n" + apiKey))
+	return filepath.Join(rc.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (rc *responseCache) load(path string) *CacheEntry {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var entry CacheEntry
+	if json.Unmarshal(content, &entry) != nil {
+		return nil
+	}
+	return &entry
+}
+
+func (rc *responseCache) store(path string, entry *CacheEntry) error {
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(rc.dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(rc.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	tmp.Close()
+	return os.Rename(tmp.Name(), path)
+}
+
+// invalidate removes the cached responses of the endpoint written to, and of
+// the endpoints it changes.
+func (rc *responseCache) invalidate(endpoint string) {
+	var prefixes []string
+	for prefix := range rc.ttls {
+		if cacheMatches(endpoint, prefix) {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	for from, to := range cacheInvalidations {
+		if cacheMatches(endpoint, from) {
+			prefixes = append(prefixes, to)
+		}
+	}
+	if len(prefixes) == 0 {
+		return
+	}
+	entries, _ := ReadCache(rc.dir)
+	for path, entry := range entries {
+		for _, prefix := range prefixes {
+			if cacheMatches(entry.Endpoint, prefix) {
+				os.Remove(path)
+				break
+			}
+		}
+	}
+}
+
+// revalidate adds the validators of a stale entry to a request, so that the
+// API can answer 304 Not Modified.
+func (entry *CacheEntry) revalidate(req *http.Request) {
+	if entry.ETag != "" {
+		req.Header.Set("If-None-Match", entry.ETag)
+	}
+	if entry.LastModified != "" {
+		req.Header.Set("If-Modified-Since", entry.LastModified)
+	}
+}
+
+func cacheMatches(endpoint, prefix string) bool {
+	return endpoint == prefix || strings.HasPrefix(endpoint, prefix+"/")
+}
+
+// ReadCache returns the entries of a cache directory, keyed by file path.
+// Unreadable files are skipped.
+func ReadCache(dir string) (map[string]*CacheEntry, error) {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	rc := &responseCache{dir: dir}
+	entries := make(map[string]*CacheEntry)
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(dir, f.Name())
+		if entry := rc.load(path); entry != nil {
+			entries[path] = entry
+		}
+	}
+	return entries, nil
+}
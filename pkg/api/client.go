@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -10,6 +11,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type RTMSClient struct {
@@ -18,6 +20,8 @@ type RTMSClient struct {
 	client       *http.Client
 	isBase64Func func(string) bool
 	debug        bool // New debug field
+	cache        *responseCache
+	ctx          context.Context
 }
 
 func NewRTMSClient(apiKey string, host string, isBase64Func func(string) bool) (*RTMSClient, error) {
@@ -48,6 +52,19 @@ func (c *RTMSClient) SetDebug(debug bool) {
 	c.debug = debug
 }
 
+// SetContext sets the context of the following requests, which are aborted
+// once it is cancelled.
+func (c *RTMSClient) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
+
+func (c *RTMSClient) context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
 func (c *RTMSClient) doRequest(method, endpoint string, query url.Values, body interface{}) ([]byte, error) {
 	u, err := url.Parse(c.baseURL + endpoint)
 	if err != nil {
@@ -66,7 +83,7 @@ func (c *RTMSClient) doRequest(method, endpoint string, query url.Values, body i
 		}
 	}
 
-	req, err := http.NewRequest(method, u.String(), bytes.NewBuffer(reqBody))
+	req, err := http.NewRequestWithContext(c.context(), method, u.String(), bytes.NewBuffer(reqBody))
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
@@ -74,6 +91,26 @@ func (c *RTMSClient) doRequest(method, endpoint string, query url.Values, body i
 	req.Header.Set("X-AUTH-TOKEN", c.apiKey)
 	req.Header.Set("Content-Type", "application/json")
 
+	// Reference data is served from the cache while it is fresh, and
+	// revalidated with the API once it is stale
+	var cached *CacheEntry
+	var cachePath string
+	var ttl time.Duration
+	if c.cache != nil && method == "GET" {
+		if ttl = c.cache.ttl(endpoint); ttl > 0 {
+			cachePath = c.cache.path(c.apiKey, method, u.String())
+			if cached = c.cache.load(cachePath); cached != nil && !c.cache.refresh {
+				if time.Now().Before(cached.Expires) {
+					if c.debug {
+						fmt.Printf("Cache hit: %s %s\n", method, u.String())
+					}
+					return cached.Body, nil
+				}
+				cached.revalidate(req)
+			}
+		}
+	}
+
 	if c.debug {
 		fmt.Printf("Request: %s %s\n", method, u.String())
 		if reqBody != nil {
@@ -97,10 +134,40 @@ func (c *RTMSClient) doRequest(method, endpoint string, query url.Values, body i
 		fmt.Printf("Response Body: %s\n", string(respBody))
 	}
 
+	if cached != nil && resp.StatusCode == http.StatusNotModified {
+		cached.StoredAt = time.Now()
+		cached.Expires = cached.StoredAt.Add(ttl)
+		if err := c.cache.store(cachePath, cached); err != nil && c.debug {
+			fmt.Printf("Cache error: %v\n", err)
+		}
+		return cached.Body, nil
+	}
+
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		return nil, fmt.Errorf("API request failed with status code %d: %s", resp.StatusCode, string(respBody))
 	}
 
+	if c.cache != nil {
+		if cachePath != "" {
+			now := time.Now()
+			err = c.cache.store(cachePath, &CacheEntry{
+				Method:       method,
+				URL:          u.String(),
+				Endpoint:     endpoint,
+				ETag:         resp.Header.Get("ETag"),
+				LastModified: resp.Header.Get("Last-Modified"),
+				StoredAt:     now,
+				Expires:      now.Add(ttl),
+				Body:         respBody,
+			})
+			if err != nil && c.debug {
+				fmt.Printf("Cache error: %v\n", err)
+			}
+		} else {
+			c.cache.invalidate(endpoint)
+		}
+	}
+
 	return respBody, nil
 }
 
@@ -700,7 +767,7 @@ func (c *RTMSClient) UploadTicketAttachment(ticketID string, filename string, co
 		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", c.baseURL+fmt.Sprintf("/tickets/%s/attachments", ticketID), body)
+	req, err := http.NewRequestWithContext(c.context(), "POST", c.baseURL+fmt.Sprintf("/tickets/%s/attachments", ticketID), body)
 	if err != nil {
 		return nil, err
 	}
@@ -836,6 +903,12 @@ func (c *RTMSClient) GetViewItems(viewType, id string, params map[string]string)
 	return c.doRequest("GET", fmt.Sprintf("/views/%s/%s", viewType, id), query, nil)
 }
 func (c *RTMSClient) StreamData(endpoint string, params map[string]string, batchSize int) (<-chan interface{}, <-chan error) {
+	return c.StreamDataUntil(endpoint, params, batchSize, nil)
+}
+
+// StreamDataUntil streams the items like StreamData, and stops requesting
+// pages once stop is closed. The caller must still drain the data channel.
+func (c *RTMSClient) StreamDataUntil(endpoint string, params map[string]string, batchSize int, stop <-chan struct{}) (<-chan interface{}, <-chan error) {
 	dataChan := make(chan interface{})
 	errChan := make(chan error, 1)
 
@@ -845,6 +918,12 @@ func (c *RTMSClient) StreamData(endpoint string, params map[string]string, batch
 
 		offset := 0
 		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
 			// Copie les paramètres originaux
 			queryParams := make(url.Values)
 			for k, v := range params {
@@ -877,7 +956,11 @@ func (c *RTMSClient) StreamData(endpoint string, params map[string]string, batch
 
 			// Envoie les données dans le canal
 			for _, item := range paginatedResp.Data {
-				dataChan <- item
+				select {
+				case dataChan <- item:
+				case <-stop:
+					return
+				}
 			}
 
 			// Vérifie si on a atteint la fin des données